@@ -0,0 +1,319 @@
+package gottolists
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gvisco/vi.sco/pkg/gotto"
+	"github.com/gvisco/vi.sco/pkg/gotto/fsm"
+)
+
+// Every list has owners, who can do anything, editors, who can change its
+// items, and viewers, who can only read it. The roles are stored in the list
+// file by user id, the users without a role get the default one of the list.
+// Lists without roles, e.g. created by older versions, are open to everyone
+// as an owner, until someone gives themselves a role.
+
+type role int
+
+const (
+	noRole role = iota
+	viewer
+	editor
+	owner
+)
+
+var roleNames = map[string]role{"viewer": viewer, "editor": editor, "owner": owner}
+
+func (r role) String() string {
+	switch r {
+	case viewer:
+		return "viewer"
+	case editor:
+		return "editor"
+	case owner:
+		return "owner"
+	default:
+		return "none"
+	}
+}
+
+func (r role) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *role) UnmarshalText(text []byte) error {
+	value, ok := roleNames[strings.ToLower(string(text))]
+	if !ok {
+		return fmt.Errorf("invalid role '%s'", text)
+	}
+	*r = value
+	return nil
+}
+
+// everyoneArg sets the default role of a list in /list role
+const everyoneArg string = "everyone"
+
+// role returns the role of a user on the list.
+func (list *List) role(user gotto.User) role {
+	if len(list.roles) == 0 {
+		return owner
+	}
+	if r, ok := list.roles[user.Id]; ok {
+		return r
+	}
+	if list.everyone != noRole {
+		return list.everyone
+	}
+	return editor
+}
+
+// checkPermission returns why the current user cannot do something on a
+// list requiring the given role, or "" if they can. Changes to the items of
+// locked lists are denied to everyone, deleting them is left to the owners.
+func (lb *ListBot) checkPermission(list *List, required role, write bool) string {
+	if write && list.locked {
+		return lb.tr("List '%s' is locked. Its owners can unlock it with /list unlock %s", list.name, list.name)
+	}
+	if list.role(lb.user) >= required {
+		return ""
+	}
+	if required == owner {
+		return lb.tr("Only the owners of list '%s' can do that", list.name)
+	}
+	return lb.tr("%s can only view list '%s'", lb.user.Mention(), list.name)
+}
+
+// permission is the role required by a command on the list it names, as
+// its first argument, and whether the command changes the items. Commands
+// with unlink only remove the lists joined from other chats from the current
+// one, which needs no role.
+type permission struct {
+	re     *regexp.Regexp
+	role   role
+	write  bool
+	unlink bool
+}
+
+// permissions maps the states reached by the commands of the Waiting state
+// to the permission they require.
+var permissions = map[fsm.State]permission{
+	deleteListConfirm: {reDelList, owner, false, true},
+	renameList:        {reListRename, owner, false, false},
+	shareList:         {reShareList, owner, false, false},
+	unshareList:       {reUnshareList, owner, false, false},
+	listRepeat:        {reListRepeat, owner, false, false},
+	listArchive:       {reListArchive, owner, false, false},
+	listUnarchive:     {reListUnarchive, owner, false, false},
+	listLock:          {reListLock, owner, false, false},
+	listUnlock:        {reListUnlock, owner, false, false},
+	listRole:          {reListRole, owner, false, false},
+	editList:          {reEditList, editor, true, false},
+	listAdd:           {reListAdd, editor, true, false},
+	listRemove:        {reListRemove, editor, true, false},
+	listCheck:         {reListCheck, editor, true, false},
+	listUncheck:       {reListUncheck, editor, true, false},
+	listUndo:          {reListUndo, editor, true, false},
+	importList:        {reImportList, editor, true, false},
+}
+
+// denied guards the commands of the Waiting state which the user has no
+// permission for, keeping the reason in ListBot.denial. The permission is
+// the one of the transition the input would take otherwise. Commands naming
+// an unknown list are let through to report it.
+func denied(ctx interface{}, s string) bool {
+	lb := ctx.(*ListBot)
+	for _, t := range lb.state.Machine().Transitions() {
+		// skip the transition guarded by denied itself
+		if t.From != waiting || t.To == waiting {
+			continue
+		}
+		if t.Guard != nil && !t.Guard(ctx, s) {
+			continue
+		}
+		p, ok := permissions[t.To]
+		if !ok {
+			return false
+		}
+		l, ok := lb.findList(p.re.FindStringSubmatch(s)[1])
+		if !ok || (p.unlink && l.linked) {
+			return false
+		}
+		lb.denial = lb.checkPermission(l, p.role, p.write)
+		return lb.denial != ""
+	}
+	return false
+}
+
+// lacks guards the input of the modal states on the current list, which the
+// user may not have the given role on.
+func lacks(required role, write bool) fsm.Guard {
+	return func(ctx interface{}, _ string) bool {
+		lb := ctx.(*ListBot)
+		lb.denial = lb.checkPermission(lb.currentList, required, write)
+		return lb.denial != ""
+	}
+}
+
+// lacksToDelete guards the confirmation of /list del, which only owners can
+// give, unless the list was joined from another chat.
+func lacksToDelete(ctx interface{}, s string) bool {
+	return !ctx.(*ListBot).currentList.linked && lacks(owner, false)(ctx, s)
+}
+
+// all guards a transition with several conditions.
+func all(guards ...fsm.Guard) fsm.Guard {
+	return func(ctx interface{}, s string) bool {
+		for _, g := range guards {
+			if !g(ctx, s) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// deniedButton tells whether the user cannot change a list with the buttons
+// of a message, telling them why in a new message so that the pressed one
+// is left untouched.
+func (bot *ListBot) deniedButton(list *List) bool {
+	msg := bot.checkPermission(list, editor, true)
+	if msg == "" {
+		return false
+	}
+	if _, err := bot.conversation.Send(&gotto.Reply{Text: msg}); err != nil {
+		log.Printf("[ERROR ListBot Cannot send permission denied] Workspace {%s} ListName {%s} Error {%s} ", bot.workspace, list.name, err)
+	}
+	return true
+}
+
+func (lb *ListBot) deny(s string) string {
+	log.Printf("[ListBot permission denied] Workspace {%s} User {%d} Input {%s} Reason {%s}", lb.workspace, lb.user.Id, summary(s), lb.denial)
+	return lb.denial
+}
+
+// setRole gives a role to a member of the chat, or the default role to
+// everyone, on a list. "none" removes the role of a member.
+func (lb *ListBot) setRole(s string) string {
+	args := reListRole.FindStringSubmatch(s)
+	l, ok := lb.findList(args[1])
+	if !ok {
+		return lb.tr("Invalid list name: %s", unquote(args[1]))
+	}
+	r := noRole
+	if args[3] != "none" {
+		r = roleNames[args[3]]
+	}
+	who := strings.TrimSpace(args[2])
+	roles := make(map[int]role, len(l.roles)+1)
+	for id, value := range l.roles {
+		roles[id] = value
+	}
+	if len(roles) == 0 {
+		// the list was open to everyone, keep the current user as an owner
+		roles[lb.user.Id] = owner
+	}
+	everyone := l.everyone
+	var msg string
+	if strings.EqualFold(who, everyoneArg) {
+		if r == owner {
+			return lb.tr("Owners must be given one by one")
+		}
+		everyone = r
+		if r == viewer {
+			msg = lb.tr("Users without a role are viewers of list '%s'", l.name)
+		} else {
+			msg = lb.tr("Users without a role are editors of list '%s'", l.name)
+		}
+	} else {
+		member, ok := lb.resolveMember(who)
+		if !ok || member.Id == 0 {
+			return lb.tr("Unknown user %s. They must write in this chat first", who)
+		}
+		if r == noRole {
+			delete(roles, member.Id)
+			msg = lb.tr("%s has the default role on list '%s'", member.Name, l.name)
+		} else {
+			roles[member.Id] = r
+			msg = lb.tr("%s is now %s of list '%s'", member.Name, lb.tr(r.String()), l.name)
+		}
+	}
+	hasOwner := false
+	for _, value := range roles {
+		hasOwner = hasOwner || value == owner
+	}
+	if !hasOwner {
+		return lb.tr("List '%s' must have an owner", l.name)
+	}
+	previousRoles, previousEveryone := l.roles, l.everyone
+	l.roles, l.everyone = roles, everyone
+	if err := l.saveToFile(); err != nil {
+		l.roles, l.everyone = previousRoles, previousEveryone
+		return lb.abort("change the roles of list", l.name, err)
+	}
+	return msg
+}
+
+// listRoles prints the roles on a list.
+func (lb *ListBot) listRoles(s string) string {
+	lname := reListRoles.FindStringSubmatch(s)[1]
+	l, ok := lb.findList(lname)
+	if !ok {
+		return lb.tr("Invalid list name: %s", unquote(lname))
+	}
+	if len(l.roles) == 0 {
+		return lb.tr("List '%s' is open to everyone. Claim it with /list role %s me owner", l.name, l.name)
+	}
+	lines := []string{}
+	for id, r := range l.roles {
+		name := lb.tr("user %d", id)
+		if m, ok := lb.members[id]; ok {
+			name = m.String()
+		}
+		lines = append(lines, fmt.Sprintf("- %s: %s", name, lb.tr(r.String())))
+	}
+	sort.Strings(lines)
+	everyone := l.everyone
+	if everyone == noRole {
+		everyone = editor
+	}
+	lines = append(lines, lb.tr("- %s else: %s", everyoneArg, lb.tr(everyone.String())))
+	if l.locked {
+		lines = append(lines, lb.tr("The list is locked"))
+	}
+	return lb.tr("Roles of list '%s':\n%s", l.name, strings.Join(lines, "\n"))
+}
+
+// setLocked makes a list read-only, or writable again.
+func (lb *ListBot) setLocked(name string, locked bool) string {
+	l, ok := lb.findList(name)
+	if !ok {
+		return lb.tr("Invalid list name: %s", unquote(name))
+	}
+	if l.locked == locked {
+		if locked {
+			return lb.tr("List '%s' is already locked", l.name)
+		}
+		return lb.tr("List '%s' is not locked", l.name)
+	}
+	l.locked = locked
+	if err := l.saveToFile(); err != nil {
+		l.locked = !locked
+		return lb.abort("lock list", l.name, err)
+	}
+	if locked {
+		return lb.tr("List '%s' is now read-only", l.name)
+	}
+	return lb.tr("List '%s' can be changed again", l.name)
+}
+
+func (lb *ListBot) lock(s string) string {
+	return lb.setLocked(reListLock.FindStringSubmatch(s)[1], true)
+}
+
+func (lb *ListBot) unlock(s string) string {
+	return lb.setLocked(reListUnlock.FindStringSubmatch(s)[1], false)
+}
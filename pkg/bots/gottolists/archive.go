@@ -0,0 +1,53 @@
+package gottolists
+
+import (
+	"strings"
+)
+
+// Archived lists, e.g. completed ones, are hidden from /list all but still
+// work with every other command. The flag is stored in the list file, so a
+// shared list is archived for all the chats it is shared with.
+
+// setArchived archives or unarchives a list.
+func (lb *ListBot) setArchived(name string, archived bool) string {
+	l, ok := lb.findList(name)
+	if !ok {
+		return lb.tr("Invalid list name: %s", unquote(name))
+	}
+	if l.archived == archived {
+		if archived {
+			return lb.tr("List '%s' is already archived", l.name)
+		}
+		return lb.tr("List '%s' is not archived", l.name)
+	}
+	l.archived = archived
+	if err := l.saveToFile(); err != nil {
+		l.archived = !archived
+		return lb.abort("archive list", l.name, err)
+	}
+	if archived {
+		return lb.tr("List '%s' archived. It is hidden from /list all, see /list archived", l.name)
+	}
+	return lb.tr("List '%s' is back in /list all", l.name)
+}
+
+func (lb *ListBot) archive(s string) string {
+	return lb.setArchived(reListArchive.FindStringSubmatch(s)[1], true)
+}
+
+func (lb *ListBot) unarchive(s string) string {
+	return lb.setArchived(reListUnarchive.FindStringSubmatch(s)[1], false)
+}
+
+func (lb *ListBot) listArchived(s string) string {
+	names := []string{}
+	for _, l := range lb.sortedLists() {
+		if l.archived {
+			names = append(names, l.name)
+		}
+	}
+	if len(names) == 0 {
+		return lb.tr("No archived lists. Archive one with /list archive <name>")
+	}
+	return lb.tr("Archived lists:") + "\n- " + strings.Join(names, "\n- ")
+}
@@ -0,0 +1,168 @@
+package gottolists
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"github.com/gvisco/vi.sco/pkg/gotto"
+	"github.com/gvisco/vi.sco/pkg/quantity"
+)
+
+// callback data are prefixed to tell them apart from the ones of other bots
+const toggleCallback string = "lists:toggle:"
+
+// maxCallbackData is the size limit of Telegram for the data of a button
+const maxCallbackData int = 64
+
+// maxItemButtons is the number of item buttons of a view, well within the
+// limits of Telegram for the keyboard of a message
+const maxItemButtons int = 50
+
+// staleView prefixes the view which replaces an out of date one
+const staleView string = "This view was out of date, nothing was changed. Here is the current list:"
+
+// fingerprint identifies an item in the data of its buttons, next to its
+// position: after the items are moved, removed or changed by another chat, an
+// old button must not act on the item which took the place of its own.
+func (item Item) fingerprint() string {
+	h := fnv.New32a()
+	h.Write([]byte(item.Text))
+	return strconv.FormatUint(uint64(h.Sum32()), 36)
+}
+
+// itemData is the data of a button acting on an item of a list.
+func itemData(prefix string, idx int, item Item, id string) string {
+	return fmt.Sprintf("%s%d:%s:%s", prefix, idx, item.fingerprint(), id)
+}
+
+// viewReply renders a page of the list (see pages.go), with a footer summing
+// all its items and their prices, a button per item, sections excluded, to
+// check or uncheck it and buttons to move to the previous and next pages.
+// When the whole list is shown the keyboard is paged anyway, at most
+// maxItemButtons items at a time. Lists whose id does not fit the button data
+// are rendered without buttons.
+func (list *List) viewReply(loc gotto.Locale, page int, size int) *gotto.Reply {
+	text := list.renderPage(loc, page, size)
+	if list.countItems() > 0 {
+		text += "\n---\n" + loc.Sprintf("Total: %s", formatTotal(loc, list.total()))
+	}
+	reply := &gotto.Reply{Text: text}
+	page, first, last, pages := list.page(page, keyboardSize(size))
+	for idx := first; idx < last && len(reply.Keyboard) < maxItemButtons; idx++ {
+		item := list.items[idx]
+		if item.Section {
+			continue
+		}
+		data := itemData(toggleCallback, idx, item, list.id)
+		if len(data) > maxCallbackData {
+			return &gotto.Reply{Text: text}
+		}
+		button := gotto.Button{Text: fmt.Sprintf("[%d] %s", idx, item), Data: data}
+		reply.Keyboard = append(reply.Keyboard, []gotto.Button{button})
+	}
+	if pages > 1 {
+		nav := []gotto.Button{}
+		if page > 0 {
+			nav = append(nav, gotto.Button{Text: loc.Sprintf("« Prev"), Data: fmt.Sprintf("%s%d:%s", pageCallback, page-1, list.id)})
+		}
+		if page < pages-1 {
+			nav = append(nav, gotto.Button{Text: loc.Sprintf("Next »"), Data: fmt.Sprintf("%s%d:%s", pageCallback, page+1, list.id)})
+		}
+		for _, button := range nav {
+			if len(button.Data) > maxCallbackData {
+				return &gotto.Reply{Text: text}
+			}
+		}
+		reply.Keyboard = append(reply.Keyboard, nav)
+	}
+	return reply
+}
+
+// keyboardSize is the number of items of a page of the keyboard of a view.
+func keyboardSize(size int) int {
+	if size <= 0 {
+		return maxItemButtons
+	}
+	return size
+}
+
+// formatTotal prints the total of a list, e.g. "5 items, €12.40".
+func formatTotal(loc gotto.Locale, total quantity.Total) string {
+	s := loc.Sprintf("%s items", quantity.FormatAmount(total.Count))
+	if total.Count == 1 {
+		s = loc.Sprintf("1 item")
+	}
+	for _, price := range total.PriceList() {
+		s += ", " + price
+	}
+	return s
+}
+
+func (bot *ListBot) OnCallback(user gotto.User, data string) *gotto.Reply {
+	var prefix string
+	switch {
+	case strings.HasPrefix(data, toggleCallback):
+		prefix = toggleCallback
+	case strings.HasPrefix(data, snoozeCallback):
+		prefix = snoozeCallback
+	case strings.HasPrefix(data, viewCallback):
+		prefix = viewCallback
+	case strings.HasPrefix(data, pageCallback):
+		prefix = pageCallback
+	default:
+		return nil
+	}
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	bot.refreshShared()
+	bot.setUser(user)
+	if prefix == viewCallback {
+		return bot.openList(strings.TrimPrefix(data, prefix))
+	}
+	// the other callbacks carry a position, of an item or a page, and a list,
+	// the item ones the fingerprint of the item too
+	n := 2
+	if prefix == toggleCallback || prefix == snoozeCallback {
+		n = 3
+	}
+	args := strings.SplitN(strings.TrimPrefix(data, prefix), ":", n)
+	if len(args) != n {
+		return nil
+	}
+	if prefix == pageCallback {
+		return bot.turnPage(args)
+	}
+	defer bot.armReminder()
+	if prefix == snoozeCallback {
+		return bot.snooze(args)
+	}
+	return bot.toggle(args)
+}
+
+// toggle checks or unchecks an item, replying with the updated view. Buttons
+// of an out of date view only refresh it.
+func (bot *ListBot) toggle(args []string) *gotto.Reply {
+	list, ok := bot.lists[args[2]]
+	if !ok {
+		return &gotto.Reply{Text: bot.tr("Invalid list: %s", args[2])}
+	}
+	if bot.deniedButton(list) {
+		return nil
+	}
+	size := bot.config.PageSize
+	idx, err := strconv.Atoi(args[0])
+	if err != nil || idx < 0 || idx >= len(list.items) || list.items[idx].Section || list.items[idx].fingerprint() != args[1] {
+		reply := list.viewReply(bot.locale(), 0, size)
+		reply.Text = bot.tr(staleView) + "\n" + reply.Text
+		return reply
+	}
+	list.items[idx].Done = !list.items[idx].Done
+	bot.action = fmt.Sprintf("toggle [%d] %s", idx, summary(list.items[idx].Text))
+	if msg := bot.saveList(list); msg != "" {
+		list.items[idx].Done = !list.items[idx].Done
+	}
+	// stay on the page of the item
+	return list.viewReply(bot.locale(), idx/keyboardSize(size), size)
+}
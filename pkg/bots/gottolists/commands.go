@@ -0,0 +1,260 @@
+package gottolists
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/gvisco/vi.sco/pkg/gotto/fsm"
+)
+
+// The state machine matches the commands exactly as written in the help.
+// parseCommand rewrites the messages of the users into that form first,
+// tolerating case and extra spaces in the command words, expanding their
+// aliases and turning a few phrases into commands. The arguments are left as
+// they are, as they may be the text of items. The "@botname" suffix of the
+// commands sent in groups is removed by gotto.
+
+// aliases are the short forms of some commands
+var aliases = map[string]string{
+	"/l":  "/list",
+	"/ls": "/list all",
+}
+
+// keywordArgs are the commands whose last argument is a keyword, like the
+// format of /list export, lowercased as the command.
+var keywordArgs = map[string]bool{
+	"/list export": true,
+	"/list role":   true,
+	"/sort":        true,
+}
+
+// phrase turns a sentence into a command on the list it names, e.g. "add
+// eggs to shopping" into "/list add shopping eggs".
+type phrase struct {
+	// re matches the sentence, capturing the argument of the command, if any
+	re *regexp.Regexp
+	// preposition introduces the name of the list, at the end of the sentence
+	preposition *regexp.Regexp
+	command     string
+}
+
+var phrases = []phrase{
+	{regexp.MustCompile(`(?is)^add (.+)$`), regexp.MustCompile(`(?i) (?:to|into|in) `), "/list add"},
+	{regexp.MustCompile(`(?is)^aggiungi (.+)$`), regexp.MustCompile(`(?i) (?:a|al|alla|in|nella|nel) `), "/list add"},
+	{regexp.MustCompile(`(?i)^(?:show|view|open|mostra|apri) (.+)$`), nil, "/list view"},
+}
+
+// articles may precede, and listWord follow, the name of a list in a phrase
+var articles = []string{"the ", "my ", "la ", "il ", "lo ", "l'"}
+
+const listWord string = " list"
+
+// parseCommand normalizes a message of a user, see above. Messages which do
+// not start with a command of the current state are left untouched.
+func (lb *ListBot) parseCommand(message string) string {
+	trimmed := strings.TrimSpace(message)
+	if !strings.HasPrefix(trimmed, "/") {
+		if lb.state.Current() == waiting {
+			if command, ok := lb.parsePhrase(trimmed); ok {
+				return command
+			}
+		}
+		return message
+	}
+	// only the first line holds the command, the others are items
+	lines := strings.SplitN(trimmed, "\n", 2)
+	word, rest := nextWord(lines[0])
+	fields := []string{strings.ToLower(word)}
+	if alias, ok := aliases[fields[0]]; ok {
+		fields = strings.Fields(alias)
+	}
+	if !lb.isCommand(fields[0]) {
+		return message
+	}
+	for len(fields) < commandLength(fields) && rest != "" {
+		word, rest = nextWord(rest)
+		fields = append(fields, strings.ToLower(word))
+	}
+	if keywordArgs[strings.Join(fields, " ")] && rest != "" {
+		last := strings.LastIndexAny(rest, " \t") + 1
+		rest = rest[:last] + strings.ToLower(rest[last:])
+	}
+	lines[0] = strings.Join(fields, " ")
+	if rest != "" {
+		lines[0] += " " + rest
+	}
+	return strings.Join(lines, "\n")
+}
+
+// nextWord splits the first word of s from the rest, without the spaces
+// between them.
+func nextWord(s string) (string, string) {
+	s = strings.TrimLeft(s, " \t")
+	end := strings.IndexAny(s, " \t")
+	if end < 0 {
+		return s, ""
+	}
+	return s[:end], strings.TrimLeft(s[end:], " \t")
+}
+
+// commandLength is the number of words of a command, given its first ones:
+// /list takes a subcommand, which is followed by another one for templates.
+func commandLength(fields []string) int {
+	switch {
+	case fields[0] != "/list":
+		return 1
+	case len(fields) > 1 && fields[1] == "template":
+		return 3
+	default:
+		return 2
+	}
+}
+
+// isCommand tells whether a word starts a command of the current state.
+func (lb *ListBot) isCommand(word string) bool {
+	for _, usage := range usages(lb.state.Machine(), lb.state.Current()) {
+		if strings.Fields(usage)[0] == word {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePhrase turns a sentence naming an existing list into a command.
+func (lb *ListBot) parsePhrase(message string) (string, bool) {
+	for _, p := range phrases {
+		args := p.re.FindStringSubmatch(message)
+		if args == nil {
+			continue
+		}
+		if p.preposition == nil {
+			if l, ok := lb.phraseList(args[1]); ok {
+				return p.command + " " + l.id, true
+			}
+			continue
+		}
+		// the name of the list follows the last preposition naming a list,
+		// as items may contain prepositions too
+		bounds := p.preposition.FindAllStringIndex(args[1], -1)
+		for i := len(bounds) - 1; i >= 0; i-- {
+			item, name := args[1][:bounds[i][0]], args[1][bounds[i][1]:]
+			if l, ok := lb.phraseList(name); ok {
+				return p.command + " " + l.id + " " + item, true
+			}
+		}
+	}
+	return "", false
+}
+
+// phraseList looks a list up by its name in a phrase, e.g. "the shopping
+// list".
+func (lb *ListBot) phraseList(name string) (*List, bool) {
+	name = strings.TrimSpace(name)
+	if l, ok := lb.findList(name); ok {
+		return l, true
+	}
+	lower := strings.ToLower(name)
+	for _, article := range articles {
+		if strings.HasPrefix(lower, article) {
+			name, lower = name[len(article):], lower[len(article):]
+			break
+		}
+	}
+	if l, ok := lb.findList(name); ok {
+		return l, true
+	}
+	if strings.HasSuffix(lower, listWord) {
+		return lb.findList(name[:len(name)-len(listWord)])
+	}
+	return nil, false
+}
+
+// usages returns the labels of the commands accepted in a state, e.g.
+// "/list view <name> [page]", which are also their syntax.
+func usages(m *fsm.Machine, from fsm.State) []string {
+	result := []string{}
+	for _, t := range m.Transitions() {
+		if t.From == from && strings.HasPrefix(t.Label, "/") && t.To != unknownCommand {
+			result = append(result, t.Label)
+		}
+	}
+	return result
+}
+
+// commandWords returns the words of a usage before its arguments, e.g.
+// "/list view" for "/list view <name> [page]".
+func commandWords(usage string) []string {
+	result := []string{}
+	for _, w := range strings.Fields(usage) {
+		if strings.HasPrefix(w, "<") || strings.HasPrefix(w, "[") {
+			break
+		}
+		result = append(result, w)
+	}
+	return result
+}
+
+// suggest returns the usages of the commands of a state closest to an
+// unrecognized input: the ones starting with the same words, which were
+// given wrong arguments, else the ones whose words are a prefix or within a
+// few typos of the given ones. Suggestions tolerate one typo more than
+// /list find, e.g. swapped letters. exact tells which case applies.
+func suggest(m *fsm.Machine, from fsm.State, input string) (result []string, exact bool) {
+	given := strings.Fields(strings.ToLower(input))
+	best := -1
+	for _, usage := range usages(m, from) {
+		words := commandWords(usage)
+		if len(given) < len(words) {
+			continue
+		}
+		score := 0
+		for i, w := range words {
+			d := distance([]rune(given[i]), []rune(w))
+			prefix := len([]rune(given[i])) >= minPrefixLength && strings.HasPrefix(w, given[i])
+			if d > maxTypos(len([]rune(w)))+1 && !prefix {
+				score = -1
+				break
+			}
+			score += d
+		}
+		switch {
+		case score < 0 || (best >= 0 && score > best):
+			continue
+		case score < best || best < 0:
+			best, result = score, nil
+		}
+		result = append(result, usage)
+	}
+	return result, best == 0
+}
+
+// suggestion tells the user how to write the command they meant, if it is
+// close to one of the state, or returns "".
+func (lb *ListBot) suggestion(from fsm.State, input string) string {
+	result, exact := suggest(lb.state.Machine(), from, input)
+	if len(result) == 0 {
+		return ""
+	}
+	if exact {
+		return lb.tr("Usage:") + "\n" + strings.Join(result, "\n")
+	}
+	return lb.tr("Unknown command. Did you mean:") + "\n" + strings.Join(result, "\n")
+}
+
+// unknownCommand suggests the closest /list commands, or prints the help.
+func (lb *ListBot) unknownCommand(s string) string {
+	if msg := lb.suggestion(waiting, s); msg != "" {
+		return msg
+	}
+	return lb.tr(helpString)
+}
+
+// invalidEdit suggests the closest edit commands, if any.
+func (lb *ListBot) invalidEdit(s string) string {
+	if strings.HasPrefix(s, "/") {
+		if msg := lb.suggestion(editInput, s); msg != "" {
+			return msg
+		}
+	}
+	return lb.tr("Invalid input. Type `/help` if needed")
+}
@@ -0,0 +1,154 @@
+package gottolists
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// reDue matches a due date at the end of an item, e.g. "@tomorrow 18:00",
+// "@friday", "@2021-06-30 9:30" or "@18:00".
+var reDue *regexp.Regexp = regexp.MustCompile(`(?i)(?:^|\s+)@(today|tomorrow|[a-z]+|\d{4}-\d{1,2}-\d{1,2}|\d{1,2}:\d{2})(?:\s+(\d{1,2}:\d{2}))?\s*$`)
+
+// defaultDueHour is the time of the day of the due dates without a time
+const defaultDueHour int = 9
+
+// dueLayout is the layout of the due dates in views and reminders
+const dueLayout string = "Mon 2 Jan 15:04"
+
+// exportDueLayout is the layout of the due dates in exports, which can be
+// read back by parseDue.
+const exportDueLayout string = "2006-01-02 15:04"
+
+var weekdays map[string]time.Weekday = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+// parseItem builds an item from its text, reading the optional due date at
+// its end. Text not resembling a date is left in the item.
+func parseItem(text string, now time.Time) Item {
+	m := reDue.FindStringSubmatchIndex(text)
+	if m == nil {
+		return Item{Text: text}
+	}
+	day := text[m[2]:m[3]]
+	clock := ""
+	if m[4] >= 0 {
+		clock = text[m[4]:m[5]]
+	}
+	due, ok := parseDue(day, clock, now)
+	if !ok || strings.TrimSpace(text[:m[0]]) == "" {
+		return Item{Text: text}
+	}
+	return Item{Text: strings.TrimSpace(text[:m[0]]), Due: &due}
+}
+
+// parseDue computes a due date from a day (today, tomorrow, a weekday, a
+// yyyy-mm-dd date or a time) and an optional time, relative to now.
+func parseDue(day string, clock string, now time.Time) (time.Time, bool) {
+	hour, min := defaultDueHour, 0
+	if strings.Contains(day, ":") {
+		if clock != "" {
+			return time.Time{}, false
+		}
+		day, clock = "", day
+	}
+	if clock != "" {
+		var ok bool
+		if hour, min, ok = parseClock(clock); !ok {
+			return time.Time{}, false
+		}
+	}
+	at := func(d time.Time) time.Time {
+		return time.Date(d.Year(), d.Month(), d.Day(), hour, min, 0, 0, now.Location())
+	}
+	day = strings.ToLower(day)
+	switch {
+	case day == "":
+		// a time alone is the next occurrence of that time
+		due := at(now)
+		if !due.After(now) {
+			due = at(now.AddDate(0, 0, 1))
+		}
+		return due, true
+	case day == "today":
+		return at(now), true
+	case day == "tomorrow":
+		return at(now.AddDate(0, 0, 1)), true
+	}
+	if wd, ok := weekdays[day]; ok {
+		due := at(now.AddDate(0, 0, (int(wd)-int(now.Weekday())+7)%7))
+		if !due.After(now) {
+			due = due.AddDate(0, 0, 7)
+		}
+		return due, true
+	}
+	d, err := time.ParseInLocation("2006-1-2", day, now.Location())
+	if err != nil {
+		return time.Time{}, false
+	}
+	return at(d), true
+}
+
+func parseClock(clock string) (int, int, bool) {
+	parts := strings.SplitN(clock, ":", 2)
+	hour, err1 := strconv.Atoi(parts[0])
+	min, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || hour > 23 || min > 59 {
+		return 0, 0, false
+	}
+	return hour, min, true
+}
+
+// dueItem is an item with a due date, with the list it belongs to.
+type dueItem struct {
+	list  *List
+	index int
+	item  Item
+}
+
+// dueItems returns the unchecked items of the lists with a due date within
+// the given interval (zero times are unbounded), sorted by date.
+func dueItems(lists []*List, after time.Time, until time.Time) []dueItem {
+	result := []dueItem{}
+	for _, list := range lists {
+		for idx, item := range list.items {
+			if item.Done || item.Due == nil {
+				continue
+			}
+			if (!after.IsZero() && !item.Due.After(after)) || (!until.IsZero() && item.Due.After(until)) {
+				continue
+			}
+			result = append(result, dueItem{list: list, index: idx, item: item})
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].item.Due.Before(*result[j].item.Due) })
+	return result
+}
+
+func (lb *ListBot) listDue(s string) string {
+	items := dueItems(lb.sortedLists(), time.Time{}, time.Time{})
+	if len(items) == 0 {
+		return lb.tr("No items with a due date")
+	}
+	now := time.Now()
+	var b strings.Builder
+	b.WriteString(lb.tr("Due items:"))
+	for _, d := range items {
+		overdue := ""
+		if d.item.Due.Before(now) {
+			overdue = " " + lb.tr("(overdue)")
+		}
+		fmt.Fprintf(&b, "\n%s%s -- %s [%d] %s", d.item.Due.Format(dueLayout), overdue, d.list.name, d.index, d.item.Text)
+	}
+	return b.String()
+}
@@ -0,0 +1,151 @@
+package gottolists
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const defaultExportFormat string = "md"
+
+// exportedList is the JSON export of a list.
+type exportedList struct {
+	Name     string    `json:"name"`
+	Exported time.Time `json:"exported"`
+	Total    int       `json:"total"`
+	Done     int       `json:"done"`
+	Items    []Item    `json:"items"`
+}
+
+func (list *List) countDone() int {
+	done := 0
+	for _, item := range list.items {
+		if item.Done {
+			done++
+		}
+	}
+	return done
+}
+
+// countItems counts the items which are not sections.
+func (list *List) countItems() int {
+	count := 0
+	for _, item := range list.items {
+		if !item.Section {
+			count++
+		}
+	}
+	return count
+}
+
+// exportIndentation is the indentation of each level in the exports, which
+// is also the width of a level for the importer.
+const exportIndentation string = "  "
+
+// exportText is the text of an item followed by its due date, if any, in a
+// format read back by parseItem.
+func (item Item) exportText() string {
+	if item.Due == nil {
+		return item.Text
+	}
+	return item.Text + " @" + item.Due.Format(exportDueLayout)
+}
+
+// export renders the list in one of the txt, md, csv and json formats. The
+// md and csv exports can be imported back with /list import.
+func (list *List) export(format string, now time.Time) ([]byte, error) {
+	var b bytes.Buffer
+	switch format {
+	case "txt":
+		fmt.Fprintf(&b, "%s (%d/%d done, exported %s)\n\n", list.name, list.countDone(), list.countItems(), now.Format(time.RFC1123))
+		for _, item := range list.items {
+			if item.Section {
+				fmt.Fprintf(&b, "\n== %s ==\n", item.Text)
+				continue
+			}
+			mark := "[ ]"
+			if item.Done {
+				mark = "[x]"
+			}
+			fmt.Fprintf(&b, "%s%s %s\n", strings.Repeat(exportIndentation, item.Level), mark, item.exportText())
+		}
+	case "md":
+		fmt.Fprintf(&b, "# %s\n\n", list.name)
+		fmt.Fprintf(&b, "> %d/%d done, exported %s\n\n", list.countDone(), list.countItems(), now.Format(time.RFC1123))
+		for _, item := range list.items {
+			if item.Section {
+				fmt.Fprintf(&b, "\n## %s\n\n", item.Text)
+				continue
+			}
+			mark := " "
+			if item.Done {
+				mark = "x"
+			}
+			fmt.Fprintf(&b, "%s- [%s] %s\n", strings.Repeat(exportIndentation, item.Level), mark, item.exportText())
+		}
+	case "csv":
+		w := csv.NewWriter(&b)
+		w.Write([]string{"item", "done", "due", "assignee", "level", "section"})
+		for _, item := range list.items {
+			due, assignee := "", ""
+			if item.Due != nil {
+				due = item.Due.Format(exportDueLayout)
+			}
+			if item.Assignee != nil {
+				assignee = item.Assignee.Name
+			}
+			w.Write([]string{item.Text, fmt.Sprint(item.Done), due, assignee, fmt.Sprint(item.Level), fmt.Sprint(item.Section)})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return nil, err
+		}
+	case "json":
+		items := list.items
+		if items == nil {
+			items = []Item{}
+		}
+		data, err := json.MarshalIndent(exportedList{
+			Name:     list.name,
+			Exported: now,
+			Total:    list.countItems(),
+			Done:     list.countDone(),
+			Items:    items,
+		}, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		b.Write(data)
+		b.WriteString("\n")
+	default:
+		return nil, fmt.Errorf("unknown format '%s'", format)
+	}
+	return b.Bytes(), nil
+}
+
+// exportAll zips the exports of all the given lists.
+func exportAll(lists []*List, format string, now time.Time) ([]byte, error) {
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	for _, list := range lists {
+		data, err := list.export(format, now)
+		if err != nil {
+			return nil, err
+		}
+		f, err := w.CreateHeader(&zip.FileHeader{Name: list.id + "." + format, Method: zip.Deflate, Modified: now})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,157 @@
+package gottolists
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+
+	"github.com/gvisco/vi.sco/pkg/gotto"
+)
+
+// viewCallback opens a list from the results of /list find
+const viewCallback string = "lists:view:"
+
+// maxFindResults is the number of matches printed by /list find
+const maxFindResults int = 20
+
+// minPrefixLength is the length of the shortest query word matching the
+// words it is a prefix of.
+const minPrefixLength int = 3
+
+// match is an item found by /list find.
+type match struct {
+	list  *List
+	index int
+	exact bool
+}
+
+// words splits a text into lowercase words.
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// maxTypos is how many typos a query word of the given length tolerates.
+func maxTypos(length int) int {
+	switch {
+	case length <= 3:
+		return 0
+	case length <= 7:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// distance is the Levenshtein distance between two words.
+func distance(a []rune, b []rune) int {
+	row := make([]int, len(b)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		diag := row[0]
+		row[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			next := diag + cost
+			if row[j]+1 < next {
+				next = row[j] + 1
+			}
+			if row[j-1]+1 < next {
+				next = row[j-1] + 1
+			}
+			diag, row[j] = row[j], next
+		}
+	}
+	return row[len(b)]
+}
+
+// fuzzyMatch tells whether every word of the query is close to a word of the
+// text: a long enough prefix of it or within maxTypos edits.
+func fuzzyMatch(query []string, text []string) bool {
+	for _, q := range query {
+		found := false
+		for _, w := range text {
+			if (len(q) >= minPrefixLength && strings.HasPrefix(w, q)) || distance([]rune(q), []rune(w)) <= maxTypos(len([]rune(q))) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return len(query) > 0
+}
+
+// find searches the items of all the lists, case-insensitively: items
+// containing the query come first, then the ones matching it fuzzily.
+func (lb *ListBot) find(query string) []match {
+	needle := strings.ToLower(strings.TrimSpace(query))
+	queryWords := words(query)
+	exact, fuzzy := []match{}, []match{}
+	for _, l := range lb.sortedLists() {
+		for idx, item := range l.items {
+			if strings.Contains(strings.ToLower(item.Text), needle) {
+				exact = append(exact, match{list: l, index: idx, exact: true})
+			} else if fuzzyMatch(queryWords, words(item.Text)) {
+				fuzzy = append(fuzzy, match{list: l, index: idx})
+			}
+		}
+	}
+	return append(exact, fuzzy...)
+}
+
+// findItems replies with the matches of a query and a button to open each
+// list they belong to.
+func (lb *ListBot) findItems(s string) string {
+	query := strings.TrimSpace(reListFind.FindStringSubmatch(s)[1])
+	found := lb.find(query)
+	if len(found) == 0 {
+		return lb.tr("Nothing found for '%s'", query)
+	}
+	var b strings.Builder
+	b.WriteString(lb.tr("Found for '%s':", query))
+	reply := &gotto.Reply{}
+	opened := make(map[string]bool)
+	for n, m := range found {
+		if n == maxFindResults {
+			b.WriteString("\n" + lb.tr("...and %d more", len(found)-n))
+			break
+		}
+		similar := ""
+		if !m.exact {
+			similar = " " + lb.tr("(similar)")
+		}
+		fmt.Fprintf(&b, "\n%s [%d] %s%s", m.list.name, m.index, m.list.items[m.index], similar)
+		data := viewCallback + m.list.id
+		if !opened[m.list.id] && len(data) <= maxCallbackData {
+			opened[m.list.id] = true
+			reply.Keyboard = append(reply.Keyboard, []gotto.Button{{Text: lb.tr("Open '%s'", m.list.name), Data: data}})
+		}
+	}
+	reply.Text = b.String()
+	if _, err := lb.conversation.Send(reply); err != nil {
+		log.Printf("[ERROR ListBot Cannot send search results] Workspace {%s} Query {%s} Error {%s} ", lb.workspace, query, err)
+		return reply.Text
+	}
+	return ""
+}
+
+// openList sends the view of a list chosen among the search results.
+func (bot *ListBot) openList(id string) *gotto.Reply {
+	list, ok := bot.lists[id]
+	if !ok {
+		return &gotto.Reply{Text: bot.tr("Invalid list: %s", id)}
+	}
+	if _, err := bot.conversation.Send(list.viewReply(bot.locale(), 0, bot.config.PageSize)); err != nil {
+		log.Printf("[ERROR ListBot Cannot send list view] Workspace {%s} ListName {%s} Error {%s} ", bot.workspace, list.name, err)
+	}
+	return nil
+}
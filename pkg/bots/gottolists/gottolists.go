@@ -1,626 +1,178 @@
 package gottolists
 
 import (
-	"bufio"
-	"fmt"
 	"io/ioutil"
 	"log"
-	"os"
 	"path/filepath"
-	"regexp"
-	"strconv"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/gvisco/vi.sco/pkg/gotto"
+	"github.com/gvisco/vi.sco/pkg/gotto/fsm"
 )
 
-const noEvent string = "<nil evt>"
-
 const helpString string = `Available commands:
 /list all -- Print the names of all the available lists
-/list view <name> -- Print the content of the a list
+/list view <name> [page] -- Print the content of the a list, tap an item to check it
 /list new <name> -- Create a new list with given name
-/list del <name> -- Delete a list
+/list new <name> from <template> -- Create a new list with the items of a template
+/list del <name> -- Delete a list, moving it to the trash
 /list edit <name> -- Edit the content of a list
+/list add <name> <item> -- Add an item to the bottom of a list
+/list rm <name> <position> -- Remove an item, or a range of items like 3-7, from a list
+/list check <name> <position> -- Mark an item of a list as done
+/list uncheck <name> <position> -- Mark an item of a list as not done
+/list rename <old> <new> -- Change the name of a list
+/list import <name> -- Add the items of a message or of a .txt, .md or .csv file to a list
+/list export <name> [txt|md|csv|json] -- Send a list as a file, "all" sends a zip of all the lists
+/list undo <name> -- Undo the last change to a list
+/list history <name> -- Print the last changes to a list
+/list share <name> -- Get a token to use a list in other chats
+/list join <token> -- Use in this chat a list shared by another chat
+/list unshare <name> -- Stop sharing a list with other chats
+/list template save <name> -- Save a list as a template, with its items unchecked
+/list template del <name> -- Delete a template
+/list templates -- Print the names of all the templates
+/list repeat <name> <schedule> [from <template>] -- Uncheck the items of a list, or replace them with a template, on a schedule like "mon 08:00", "daily 20:00" or "every 12h". "off" stops it
+/list repeats -- Print the lists which repeat
+/list find <text> -- Search the items of all the lists, tolerating typos
+/list due -- Print the items with a due date of all the lists
+/list mine -- Print the items assigned to you in all the lists
+/list trash -- Print the deleted lists, which are purged after a while
+/list restore <name> -- Bring back a deleted list from the trash
+/list archive <name> -- Hide a list, e.g. a completed one, from /list all without deleting it
+/list unarchive <name> -- Show an archived list in /list all again
+/list archived -- Print the archived lists
+/list role <name> <user> <owner|editor|viewer|none> -- Change the role of a member, or of "everyone" else, on a list. Editors change the items, owners can do anything
+/list roles <name> -- Print the roles on a list
+/list lock <name> -- Make a list read-only, also for /list repeat. Its owners can still delete it
+/list unlock <name> -- Make a locked list writable again
+/list pin <name> -- Pin a message with the list, kept up to date
+/list unpin <name> -- Stop updating the pinned message of a list
+Names can contain spaces: write them "within quotes" when followed by other arguments.
+Items can have a due date, e.g. "buy milk @tomorrow 18:00", "@friday", "@2021-06-30" or "@18:00": the chat is reminded when they are due.
+Items can have a quantity and a price, e.g. "2x milk €1.20" or "500g flour": adding the same product again sums them, and /list view shows the totals.
+/l and /ls are short for /list and /list all, and lists can be changed with phrases like "add eggs to shopping" or "show shopping".
 /list help -- Print this help message
+/lang -- Print or change your language
 `
 
 const editHelpString string = `Available commands for edit:
-/append <item> -- Add a new item to the bottom of the list
-/rm <position> -- Remove an item
+/append <item> -- Add a new item to the bottom of the list, or one per line. End an item with "@<day> [hh:mm]" to set a due date
+/rm <position> -- Remove an item, or a range of items like 3-7
 /add <position> <item> -- Add an item in given position
-/mv <from> <to> -- Move an item from one position to another
+/mv <from> <to> -- Move an item, or a range of items like 2-4, to another position
 /edit <position> <item> -- Replace the item at a given position
+/check <position> -- Mark an item as done
+/uncheck <position> -- Mark an item as not done
+/clear-done -- Remove all the items marked as done
+/sort [asc|desc|done|due] -- Sort the items within each section by text, putting the unchecked ones first or by due date
+/dedup -- Remove the repeated items
+/reverse -- Reverse the order of the items
+/shuffle -- Shuffle the items
+/filter [text] -- Only show the items containing a text, or all of them if no text is given
+/page <number> -- Show a page of a long list. After each command only the changed items are shown
+/section <name> -- Add a section header to the bottom of the list
+/indent <position> -- Make an item, or a range of items, a child of the previous one
+/outdent <position> -- Move an item, or a range of items, one level up
+/assign <position> <user> -- Assign an item to a member of the chat: @username, first name or "me"
+/unassign <position> -- Remove the assignee of an item
+/undo -- Undo the last change
+/redo -- Redo the last undone change
 /end -- Stop editing the list
 /help -- Print this help message
 `
 
-var reListView *regexp.Regexp = regexp.MustCompile(`/list view ([^ ]+)$`)
-var reNewList *regexp.Regexp = regexp.MustCompile(`/list new ([^ ]+)$`)
-var reDelList *regexp.Regexp = regexp.MustCompile(`/list del ([^ ]+)$`)
-var reEditList *regexp.Regexp = regexp.MustCompile(`/list edit ([^ ]+)$`)
-var reUnrecognizedList *regexp.Regexp = regexp.MustCompile(`/list(.+)$`)
-var reEditAppend *regexp.Regexp = regexp.MustCompile(`/append (.+)$`)
-var reEditRemomve *regexp.Regexp = regexp.MustCompile(`/rm (\d+)$`)
-var reEditAdd *regexp.Regexp = regexp.MustCompile(`/add (\d+) (.+)$`)
-var reEditMove *regexp.Regexp = regexp.MustCompile(`/mv (\d+) (\d+)$`)
-var reEditEdit *regexp.Regexp = regexp.MustCompile(`/edit (\d+) (.+)$`)
-
-type state int
-
-const (
-	waiting state = iota
-	help
-	listAll
-	viewList
-	newList
-	newInput
-	newDone
-	deleteListConfirm
-	deleteListConfirmInput
-	deleteListDone
-	editList
-	editInput
-	editAppend
-	editRemove
-	editAdd
-	editMove
-	editEdit
-	editInvalid
-	editDone
-	editHelp
-)
-
-func (s state) String() string {
-	switch s {
-	case waiting:
-		return "Waiting"
-	case help:
-		return "Help"
-	case listAll:
-		return "ListAll"
-	case viewList:
-		return "ViewList"
-	case newList:
-		return "NewList"
-	case newInput:
-		return "NewInput"
-	case newDone:
-		return "NewDone"
-	case deleteListConfirmInput:
-		return "DeleteListConfirmInput"
-	case deleteListConfirm:
-		return "DeleteListConfirm"
-	case deleteListDone:
-		return "DeleteListDone"
-	case editList:
-		return "EditList"
-	case editInput:
-		return "EditInput"
-	case editAppend:
-		return "EditAppend"
-	case editRemove:
-		return "EditRemove"
-	case editAdd:
-		return "EditAdd"
-	case editMove:
-		return "EditMove"
-	case editEdit:
-		return "EditEdit"
-	case editInvalid:
-		return "EditInvalid"
-	case editDone:
-		return "EditDone"
-	case editHelp:
-		return "EditHelp"
-	default:
-		return fmt.Sprintf("%d", int(s))
-	}
-}
-
-type StateMachine struct {
-	current state
-	nodes   map[state]Node
+// Config holds the settings of the gottolists bot, read from the
+// [gottolists] section of the configuration file.
+type Config struct {
+	// StateExpiry is how long an interrupted modal interaction (e.g. a list
+	// being edited) survives a restart. Zero means forever.
+	StateExpiry time.Duration
+	// Timeouts maps the modal states (NewInput, EditInput and
+	// DeleteListConfirmInput) to how long they wait for user input before
+	// going back to Waiting. States missing from the configuration keep
+	// their default timeout, states without a timeout wait forever.
+	Timeouts map[string]time.Duration
+	// ItemSeparators are the characters splitting a message into several
+	// items, besides newlines (e.g. ",;"). Empty disables the splitting.
+	ItemSeparators string
+	// Snooze is how much the reminder of a due item is postponed by its
+	// snooze button.
+	Snooze time.Duration
+	// PageSize is the number of items shown at once by /list view and the
+	// edit mode. Zero shows the whole lists.
+	PageSize int
+	// TrashRetention is how long deleted lists are kept in the trash. Zero
+	// means forever.
+	TrashRetention time.Duration
 }
 
-type Node struct {
-	activate func(*ListBot, string) string
-	edges    []Edge
-}
-
-type Edge struct {
-	matcher func(string) bool
-	dest    state
-}
-
-func initStateMachine() *StateMachine {
-	return &StateMachine{
-		current: waiting,
-		nodes: map[state]Node{
-			waiting: {
-				activate: func(bot *ListBot, msg string) string { return "" },
-				edges: []Edge{
-					{
-						matcher: func(s string) bool { return s == "/list help" },
-						dest:    help,
-					},
-					{
-						matcher: func(s string) bool { return s == "/list all" },
-						dest:    listAll,
-					},
-					{
-						matcher: func(s string) bool { return reListView.MatchString(s) },
-						dest:    viewList,
-					},
-					{
-						matcher: func(s string) bool { return reNewList.MatchString(s) },
-						dest:    newList,
-					},
-					{
-						matcher: func(s string) bool { return reDelList.MatchString(s) },
-						dest:    deleteListConfirm,
-					},
-					{
-						matcher: func(s string) bool { return reEditList.MatchString(s) },
-						dest:    editList,
-					},
-					{
-						matcher: func(s string) bool { return reUnrecognizedList.MatchString(s) },
-						dest:    help,
-					},
-				},
-			},
-			help: {
-				activate: func(lb *ListBot, s string) string { return helpString },
-				edges: []Edge{
-					{
-						matcher: func(s string) bool { return s == noEvent },
-						dest:    waiting,
-					},
-				},
-			},
-			listAll: {
-				activate: func(lb *ListBot, s string) string {
-					result := "Your lists:"
-					for _, l := range lb.lists {
-						result = fmt.Sprintf("%s\n- %s", result, l.name)
-					}
-					return result
-				},
-				edges: []Edge{
-					{
-						matcher: func(s string) bool { return s == noEvent },
-						dest:    waiting,
-					},
-				},
-			},
-			viewList: {
-				activate: func(lb *ListBot, s string) string {
-					lname := reListView.FindStringSubmatch(s)[1]
-					l, ok := lb.lists[lname]
-					if !ok {
-						return fmt.Sprintf("Invalid list name: %s", lname)
-					}
-					result := fmt.Sprintf("--- %s ---", lname)
-					for idx, val := range l.items {
-						result = fmt.Sprintf("%s\n[%d] %s", result, idx, val)
-					}
-					return result
-				},
-				edges: []Edge{
-					{
-						matcher: func(s string) bool { return s == noEvent },
-						dest:    waiting,
-					},
-				},
-			},
-			newList: {
-				activate: func(lb *ListBot, s string) string {
-					lname := reNewList.FindStringSubmatch(s)[1]
-					_, ok := lb.lists[lname]
-					if ok {
-						lb.state.current = waiting
-						return fmt.Sprintf("A list with name '%s' already exists", lname)
-					}
-					list := &List{
-						name:     lname,
-						filePath: lb.workspace + "/" + lname + ".list",
-						items:    []string{},
-					}
-					err := list.saveToFile()
-					if err != nil {
-						lb.state.current = waiting
-						log.Printf("[ERROR ListBot Cannot save list to file] Workspace {%s} ListName {%s} Error {%s} ", lb.workspace, lname, err)
-						return fmt.Sprintf("Cannot save list '%s'. An error occurred", lname)
-					}
-					lb.lists[lname] = list
-					lb.currentList = list
-					return fmt.Sprintf("I'm listening. Add new items to list '%s'.\nWrite `/end` to complete", lname)
-				},
-				edges: []Edge{
-					{
-						matcher: func(s string) bool { return s == noEvent },
-						dest:    newInput,
-					},
-				},
-			},
-			newInput: {
-				activate: func(lb *ListBot, s string) string {
-					if s == noEvent {
-						return ""
-					}
-
-					lb.currentList.addItem(s)
-					lname := lb.currentList.name
-					err := lb.currentList.saveToFile()
-					if err != nil {
-						lb.state.current = waiting
-						log.Printf("[ERROR ListBot Cannot save list to file] Workspace {%s} ListName {%s} Error {%s} ", lb.workspace, lname, err)
-						return fmt.Sprintf("Cannot save list '%s'. An error occurred", lname)
-					}
-					return ""
-				},
-				edges: []Edge{
-					{
-						matcher: func(s string) bool { return s == "/end" },
-						dest:    newDone,
-					},
-					{
-						matcher: func(s string) bool { return s != noEvent },
-						dest:    newInput,
-					},
-				},
-			},
-			newDone: {
-				activate: func(lb *ListBot, s string) string {
-					return fmt.Sprintf("New list '%s' created with %d items", lb.currentList.name, len(lb.currentList.items))
-				},
-				edges: []Edge{
-					{
-						matcher: func(s string) bool { return s == noEvent },
-						dest:    waiting,
-					},
-				},
-			},
-			deleteListConfirm: {
-				activate: func(lb *ListBot, s string) string {
-					lname := reDelList.FindStringSubmatch(s)[1]
-					l, ok := lb.lists[lname]
-					if !ok {
-						lb.state.current = waiting
-						return fmt.Sprintf("Invalid list name: %s", lname)
-					}
-					lb.currentList = l
-					return fmt.Sprintf("Are you sure you want to delete list '%s'?", lb.currentList.name)
-				},
-				edges: []Edge{
-					{
-						matcher: func(s string) bool { return s == noEvent },
-						dest:    deleteListConfirmInput,
-					},
-				},
-			},
-			deleteListConfirmInput: {
-				activate: func(lb *ListBot, s string) string {
-					return "Please reply 'yes' or 'no'"
-				},
-				edges: []Edge{
-					{
-						matcher: func(s string) bool { return s == "no" },
-						dest:    waiting,
-					},
-					{
-						matcher: func(s string) bool { return s == "yes" },
-						dest:    deleteListDone,
-					},
-					{
-						matcher: func(s string) bool { return s != noEvent },
-						dest:    deleteListConfirmInput,
-					},
-				},
-			},
-			deleteListDone: {
-				activate: func(lb *ListBot, s string) string {
-					toBeDeleted := lb.currentList
-					err := os.Remove(toBeDeleted.filePath)
-					if err != nil {
-						lb.state.current = waiting
-						log.Printf("[ERROR ListBot Cannot delete list file] Workspace {%s} ListName {%s} Error {%s} ", lb.workspace, toBeDeleted.name, err)
-						return fmt.Sprintf("Cannot delete list '%s'. An error occurred", toBeDeleted.name)
-					}
-					lb.currentList = nil
-					delete(lb.lists, toBeDeleted.name)
-					return fmt.Sprintf("List '%s' succesfully deleted", toBeDeleted.name)
-				},
-				edges: []Edge{
-					{
-						matcher: func(s string) bool { return s == noEvent },
-						dest:    waiting,
-					},
-				},
-			},
-			editList: {
-				activate: func(lb *ListBot, s string) string {
-					lname := reEditList.FindStringSubmatch(s)[1]
-					l, ok := lb.lists[lname]
-					if !ok {
-						lb.state.current = waiting
-						return fmt.Sprintf("Invalid list name: %s", lname)
-					}
-					lb.currentList = l
-					return fmt.Sprintf("Editing list '%s'.\nWrite `/help` to see the available commands", lb.currentList.name)
-				},
-				edges: []Edge{
-					{
-						matcher: func(s string) bool { return s == noEvent },
-						dest:    editInput,
-					},
-				},
-			},
-			editInput: {
-				activate: func(lb *ListBot, s string) string {
-					result := fmt.Sprintf("--- %s ---", lb.currentList.name)
-					for idx, val := range lb.currentList.items {
-						result = fmt.Sprintf("%s\n[%d] %s", result, idx, val)
-					}
-					return result
-				},
-				edges: []Edge{
-					{
-						matcher: func(s string) bool { return s == "/help" },
-						dest:    editHelp,
-					},
-					{
-						matcher: func(s string) bool { return s == "/end" },
-						dest:    editDone,
-					},
-					{
-						matcher: func(s string) bool { return reEditAppend.MatchString(s) },
-						dest:    editAppend,
-					},
-					{
-						matcher: func(s string) bool { return reEditRemomve.MatchString(s) },
-						dest:    editRemove,
-					},
-					{
-						matcher: func(s string) bool { return reEditAdd.MatchString(s) },
-						dest:    editAdd,
-					},
-					{
-						matcher: func(s string) bool { return reEditMove.MatchString(s) },
-						dest:    editMove,
-					},
-					{
-						matcher: func(s string) bool { return reEditEdit.MatchString(s) },
-						dest:    editEdit,
-					},
-					{
-						matcher: func(s string) bool { return s != noEvent },
-						dest:    editInvalid,
-					},
-				},
-			},
-			editAppend: {
-				activate: func(lb *ListBot, s string) string {
-					item := reEditAppend.FindStringSubmatch(s)[1]
-					lb.currentList.items = append(lb.currentList.items, item)
-					return ""
-				},
-				edges: []Edge{
-					{
-						matcher: func(s string) bool { return s == noEvent },
-						dest:    editInput,
-					},
-				},
-			},
-			editRemove: {
-				activate: func(lb *ListBot, s string) string {
-					items := lb.currentList.items
-					idx, err := strconv.Atoi(reEditRemomve.FindStringSubmatch(s)[1])
-					if err != nil || idx < 0 || idx >= len(items) {
-						return fmt.Sprintf("Invalid index %s", reEditEdit.FindStringSubmatch(s)[1])
-					}
-					lb.currentList.remove(idx)
-					lname := lb.currentList.name
-					err = lb.currentList.saveToFile()
-					if err != nil {
-						lb.state.current = waiting
-						log.Printf("[ERROR ListBot Cannot save list to file] Workspace {%s} ListName {%s} Error {%s} ", lb.workspace, lname, err)
-						return fmt.Sprintf("Cannot save list '%s'. An error occurred", lname)
-					}
-					return ""
-				},
-				edges: []Edge{
-					{
-						matcher: func(s string) bool { return s == noEvent },
-						dest:    editInput,
-					},
-				},
-			},
-			editAdd: {
-				activate: func(lb *ListBot, s string) string {
-					items := lb.currentList.items
-					idx, err := strconv.Atoi(reEditAdd.FindStringSubmatch(s)[1])
-					if err != nil || idx < 0 || idx >= len(items) {
-						return fmt.Sprintf("Invalid index %s", reEditEdit.FindStringSubmatch(s)[1])
-					}
-					item := reEditAdd.FindStringSubmatch(s)[2]
-					lb.currentList.insert(item, idx)
-					lname := lb.currentList.name
-					err = lb.currentList.saveToFile()
-					if err != nil {
-						lb.state.current = waiting
-						log.Printf("[ERROR ListBot Cannot save list to file] Workspace {%s} ListName {%s} Error {%s} ", lb.workspace, lname, err)
-						return fmt.Sprintf("Cannot save list '%s'. An error occurred", lname)
-					}
-
-					return ""
-				},
-				edges: []Edge{
-					{
-						matcher: func(s string) bool { return s == noEvent },
-						dest:    editInput,
-					},
-				},
-			},
-			editMove: {
-				activate: func(lb *ListBot, s string) string {
-					items := lb.currentList.items
-					from, err1 := strconv.Atoi(reEditMove.FindStringSubmatch(s)[1])
-					if err1 != nil || from < 0 || from >= len(items) {
-						return fmt.Sprintf("Invalid 'from' index %s", reEditMove.FindStringSubmatch(s)[1])
-					}
-					to, err2 := strconv.Atoi(reEditMove.FindStringSubmatch(s)[2])
-					if err2 != nil || to < 0 || to >= len(items) {
-						return fmt.Sprintf("Invalid 'to' index %s", reEditMove.FindStringSubmatch(s)[2])
-					}
-					lb.currentList.move(from, to)
-					lname := lb.currentList.name
-					err := lb.currentList.saveToFile()
-					if err != nil {
-						lb.state.current = waiting
-						log.Printf("[ERROR ListBot Cannot save list to file] Workspace {%s} ListName {%s} Error {%s} ", lb.workspace, lname, err)
-						return fmt.Sprintf("Cannot save list '%s'. An error occurred", lname)
-					}
-					return ""
-				},
-				edges: []Edge{
-					{
-						matcher: func(s string) bool { return s == noEvent },
-						dest:    editInput,
-					},
-				},
-			},
-			editEdit: {
-				activate: func(lb *ListBot, s string) string {
-					idx, err := strconv.Atoi(reEditEdit.FindStringSubmatch(s)[1])
-					if err != nil || idx < 0 || idx >= len(lb.currentList.items) {
-						return fmt.Sprintf("Invalid index %s", reEditEdit.FindStringSubmatch(s)[1])
-					}
-					item := reEditEdit.FindStringSubmatch(s)[2]
-					lb.currentList.items[idx] = item
-
-					lname := lb.currentList.name
-					err = lb.currentList.saveToFile()
-					if err != nil {
-						lb.state.current = waiting
-						log.Printf("[ERROR ListBot Cannot save list to file] Workspace {%s} ListName {%s} Error {%s} ", lb.workspace, lname, err)
-						return fmt.Sprintf("Cannot save list '%s'. An error occurred", lname)
-					}
-					return ""
-				},
-				edges: []Edge{
-					{
-						matcher: func(s string) bool { return s == noEvent },
-						dest:    editInput,
-					},
-				},
-			},
-			editInvalid: {
-				activate: func(lb *ListBot, s string) string { return "Invalid input. Type `/help` if needed" },
-				edges: []Edge{
-					{
-						matcher: func(s string) bool { return s == noEvent },
-						dest:    editInput,
-					},
-				},
-			},
-			editDone: {
-				activate: func(lb *ListBot, s string) string {
-					return fmt.Sprintf("Edit of lis '%s' complete", lb.currentList.name)
-				},
-				edges: []Edge{
-					{
-						matcher: func(s string) bool { return s == noEvent },
-						dest:    waiting,
-					},
-				},
-			},
-			editHelp: {
-				activate: func(lb *ListBot, s string) string { return editHelpString },
-				edges: []Edge{
-					{
-						matcher: func(s string) bool { return s == noEvent },
-						dest:    editInput,
-					},
-				},
-			},
+func DefaultConfig() Config {
+	return Config{
+		StateExpiry: time.Hour,
+		Timeouts: map[string]time.Duration{
+			newInput.String():               15 * time.Minute,
+			editInput.String():              15 * time.Minute,
+			deleteListConfirmInput.String(): 5 * time.Minute,
+			importInput.String():            15 * time.Minute,
 		},
+		Snooze:         time.Hour,
+		TrashRetention: 30 * 24 * time.Hour,
+		PageSize:       20,
 	}
 }
 
-type List struct {
-	name     string
-	filePath string
-	items    []string
-}
-
-func (list *List) loadFromFile() error {
-	file, err := os.Open(list.filePath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	var lines []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
-	}
-	list.items = lines
-
-	return scanner.Err()
-}
-
-func (list *List) saveToFile() error {
-	file, err := os.Create(list.filePath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	w := bufio.NewWriter(file)
-	for _, line := range list.items {
-		fmt.Fprintln(w, line)
-	}
-	return w.Flush()
-}
-
-func (list *List) addItem(s string) {
-	list.items = append(list.items, s)
-}
-
-func (list *List) insert(value string, index int) {
-	list.items = append(list.items[:index], append([]string{value}, list.items[index:]...)...)
-}
-
-func (list *List) remove(index int) {
-	list.items = append(list.items[:index], list.items[index+1:]...)
-}
-
-func (list *List) move(srcIndex int, dstIndex int) {
-	value := list.items[srcIndex]
-	list.remove(srcIndex)
-	list.insert(value, dstIndex)
-}
-
 type ListBotFactory struct {
+	config Config
 }
 
 type ListBot struct {
-	workspace   string
-	lists       map[string]*List
-	state       *StateMachine
-	currentList *List
+	workspace    string
+	lists        map[string]*List
+	state        *fsm.Instance
+	currentList  *List
+	failed       bool
+	config       Config
+	conversation *gotto.Conversation
+	timeout      *gotto.Timer
+	reminder     *gotto.Timer
+	purge        *gotto.Timer
+	lastReminder time.Time
+	recurrences  map[string]*recurrence
+	document     *document
+	// filter hides the items not containing it while editing
+	filter string
+	// page is the page shown in edit mode, on entering it or after /page
+	page     int
+	showPage bool
+	// before are the items of the list being edited before the update
+	before []Item
+	// saved is the state last written by saveState
+	saved savedState
+	// the user and the action of the update being handled
+	user   gotto.User
+	action string
+	// members are the users seen in the chat, by id
+	members map[int]gotto.User
+	// denial is why the user cannot run the input being handled
+	denial string
 }
 
-func NewFactory() *ListBotFactory {
-	return &ListBotFactory{}
+// document is a file received while importing items.
+type document struct {
+	name    string
+	content []byte
 }
 
-func (*ListBotFactory) CreateBot(workspace string) (gotto.GottoBot, error) {
+func NewFactory(config Config) *ListBotFactory {
+	return &ListBotFactory{config: config}
+}
+
+func (factory *ListBotFactory) CreateBot(conversation *gotto.Conversation) (gotto.GottoBot, error) {
+	workspace := conversation.Workspace()
 	log.Printf("[Create new ListBot] Workspace {%s}", workspace)
-	sm := initStateMachine()
 	// read existing lists
 	files, err := ioutil.ReadDir(workspace)
 	if err != nil {
@@ -630,7 +182,7 @@ func (*ListBotFactory) CreateBot(workspace string) (gotto.GottoBot, error) {
 
 	lists := make(map[string]*List)
 	for _, file := range files {
-		if strings.HasSuffix(file.Name(), ".list") {
+		if strings.HasSuffix(file.Name(), listFileExt) {
 			fileName := workspace + "/" + file.Name()
 			name := strings.TrimSuffix(file.Name(), filepath.Ext(fileName))
 			list := &List{name: name, filePath: fileName}
@@ -639,37 +191,134 @@ func (*ListBotFactory) CreateBot(workspace string) (gotto.GottoBot, error) {
 				log.Printf("[ERROR ListBot cannot read list from file] File {%s} Error {%s}", fileName, err)
 				continue
 			}
-			lists[name] = list
+			list.id = slugify(list.name)
+			if _, ok := lists[list.id]; ok || list.id == "" {
+				log.Printf("[ERROR ListBot ignoring list with clashing or empty name] File {%s} ListName {%s}", fileName, list.name)
+				continue
+			}
+			lists[list.id] = list
 		}
 	}
 
-	log.Printf("[ListBot created] Workspace {%s} Lists {%d}", workspace, len(lists))
-	return &ListBot{workspace: workspace, lists: lists, state: sm, currentList: nil}, nil
+	bot := &ListBot{
+		workspace:    workspace,
+		lists:        lists,
+		currentList:  nil,
+		config:       factory.config,
+		conversation: conversation,
+	}
+	bot.state = listMachine.NewInstance(bot)
+	bot.loadMembers()
+	bot.loadLinks(files)
+	bot.watchPins()
+	bot.restoreState(factory.config.StateExpiry)
+	bot.armTimeout()
+	bot.loadRecurrences()
+	bot.loadReminders()
+	bot.armReminder()
+	bot.purgeTrash()
+	log.Printf("[ListBot created] Workspace {%s} Lists {%d} State {%s}", workspace, len(lists), bot.state.Current())
+	return bot, nil
 }
 
-func (bot *ListBot) OnUpdate(userId string, userName string, message string) string {
-	return changeState(bot, message)
+// maxSummaryLength is the length of the longest action stored in a journal
+const maxSummaryLength int = 40
+
+// summary shortens a message to describe it in a journal.
+func summary(message string) string {
+	message = strings.TrimSpace(strings.SplitN(message, "\n", 2)[0])
+	if runes := []rune(message); len(runes) > maxSummaryLength {
+		return string(runes[:maxSummaryLength-1]) + "…"
+	}
+	return message
 }
 
-func changeState(bot *ListBot, message string) string {
-	result := ""
-	curr := bot.state.nodes[bot.state.current]
-	for _, e := range curr.edges {
-		if e.matcher(message) {
-			log.Printf("[ListBot changing state] From {%+v} To {%+v}", bot.state.current, e.dest)
-			bot.state.current = e.dest
-			node := bot.state.nodes[e.dest]
-			reply := node.activate(bot, message)
-			if reply != "" {
-				result = result + "\n" + reply
-			}
-			// try to follow recursively the <nil> path
-			reply = changeState(bot, noEvent)
-			if reply != "" {
-				result = result + "\n" + reply
-			}
-			break
-		}
+// locale is the language of the user of the update being handled, or of the
+// last one for the updates without a user, like reminders.
+func (lb *ListBot) locale() gotto.Locale {
+	return lb.conversation.Locale(lb.user)
+}
+
+// tr translates a message for the user, see gotto.Locale.Sprintf.
+func (lb *ListBot) tr(format string, args ...interface{}) string {
+	return lb.locale().Sprintf(format, args...)
+}
+
+func (bot *ListBot) sortedLists() []*List {
+	result := make([]*List, 0, len(bot.lists))
+	for _, l := range bot.lists {
+		result = append(result, l)
 	}
+	sort.Slice(result, func(i, j int) bool { return result[i].id < result[j].id })
 	return result
 }
+
+func (bot *ListBot) OnUpdate(user gotto.User, message string) string {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	notice := bot.refreshShared()
+
+	bot.failed = false
+	bot.setUser(user)
+	// the journal records what the user wrote, not the command it became
+	bot.action = summary(message)
+	message = bot.parseCommand(message)
+	if bot.currentList != nil {
+		bot.before = append([]Item{}, bot.currentList.items...)
+	}
+	reply := notice + bot.state.Fire(message)
+	if err := bot.saveState(); err != nil {
+		log.Printf("[ERROR ListBot cannot save state] Workspace {%s} Error {%s}", bot.workspace, err)
+	}
+	bot.armTimeout()
+	bot.armReminder()
+	return reply
+}
+
+// OnDocument feeds a received file to the state machine, which only accepts
+// it while importing.
+func (bot *ListBot) OnDocument(user gotto.User, fileName string, content []byte) string {
+	if bot.state.Current() != importInput {
+		return ""
+	}
+	bot.document = &document{name: fileName, content: content}
+	defer func() { bot.document = nil }()
+	return bot.OnUpdate(user, fileName)
+}
+
+// armTimeout (re)starts the inactivity timer of the current state, if any.
+func (bot *ListBot) armTimeout() {
+	if bot.timeout != nil {
+		bot.timeout.Stop()
+		bot.timeout = nil
+	}
+	d, ok := bot.config.Timeouts[bot.state.Current().String()]
+	if !ok || d <= 0 {
+		return
+	}
+	expired := bot.state.Current()
+	bot.timeout = bot.conversation.AfterFunc(d, func() string {
+		bot.timeout = nil
+		if bot.state.Current() != expired {
+			return ""
+		}
+		log.Printf("[ListBot state timed out] Workspace {%s} State {%s} Timeout {%s}", bot.workspace, expired, d)
+		bot.state.Set(waiting)
+		if err := bot.saveState(); err != nil {
+			log.Printf("[ERROR ListBot cannot save state] Workspace {%s} Error {%s}", bot.workspace, err)
+		}
+		lname := bot.currentList.name
+		switch expired {
+		case newInput:
+			return bot.tr("No new items for %s: list '%s' created with %d items", d, lname, len(bot.currentList.items))
+		case editInput:
+			return bot.tr("No edits for %s: edit of list '%s' complete", d, lname)
+		case deleteListConfirmInput:
+			return bot.tr("No answer for %s: list '%s' was not deleted", d, lname)
+		case importInput:
+			return bot.tr("Nothing received for %s: import into list '%s' cancelled", d, lname)
+		default:
+			return bot.tr("No input for %s: going back to waiting", d)
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package gottolists
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// reMarkdownItem matches the bullet or number and the optional checkbox of a
+// markdown list item.
+var reMarkdownItem *regexp.Regexp = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+(?:\[([ xX])\]\s+)?`)
+
+// splitItems splits a message into items: one per line and, if separators
+// are given, one per separated chunk within each line.
+func splitItems(text string, separators string) []string {
+	items := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		chunks := []string{line}
+		if separators != "" {
+			chunks = strings.FieldsFunc(line, func(r rune) bool { return strings.ContainsRune(separators, r) })
+		}
+		for _, chunk := range chunks {
+			if chunk = strings.TrimSpace(chunk); chunk != "" {
+				items = append(items, chunk)
+			}
+		}
+	}
+	return items
+}
+
+// parseItems reads the items of an imported document. Plain text and
+// markdown are read one item per line, ignoring bullets, numbers, titles and
+// quotes and honouring "[x]" checkboxes, due dates, indentation and "##"
+// headings, which become sections. CSV files are read from the "item" (or
+// "text"), "done", "due", "assignee", "level" and "section" columns if there
+// is a header, from the first column otherwise.
+func parseItems(fileName string, content []byte) ([]Item, error) {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		return parseCsvItems(content)
+	case ".txt", ".md", "":
+		return parseTextItems(string(content)), nil
+	default:
+		return nil, fmt.Errorf("unsupported file type '%s'", filepath.Ext(fileName))
+	}
+}
+
+func parseTextItems(text string) []Item {
+	now := time.Now()
+	items := []Item{}
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "##") {
+			if section := strings.TrimSpace(strings.TrimLeft(trimmed, "#")); section != "" {
+				items = append(items, Item{Text: section, Section: true})
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ">") {
+			continue
+		}
+		indent := strings.ReplaceAll(line[:len(line)-len(strings.TrimLeft(line, " \t"))], "\t", exportIndentation)
+		done := false
+		if m := reMarkdownItem.FindStringSubmatch(line); m != nil {
+			done = strings.EqualFold(m[1], "x")
+			line = line[len(m[0]):]
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			item := parseItem(line, now)
+			item.Done = done
+			item.Level = len(indent) / len(exportIndentation)
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func parseCsvItems(content []byte) ([]Item, error) {
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	textCol, doneCol, dueCol, assigneeCol, levelCol, sectionCol := 0, -1, -1, -1, -1, -1
+	if len(records) > 0 {
+		header := false
+		for idx, name := range records[0] {
+			switch strings.ToLower(strings.TrimSpace(name)) {
+			case "item", "text":
+				textCol, header = idx, true
+			case "done":
+				doneCol, header = idx, true
+			case "due":
+				dueCol, header = idx, true
+			case "assignee":
+				assigneeCol, header = idx, true
+			case "level":
+				levelCol, header = idx, true
+			case "section":
+				sectionCol, header = idx, true
+			}
+		}
+		if header {
+			records = records[1:]
+		}
+	}
+	items := []Item{}
+	for _, record := range records {
+		if textCol >= len(record) || strings.TrimSpace(record[textCol]) == "" {
+			continue
+		}
+		item := Item{Text: strings.TrimSpace(record[textCol])}
+		if doneCol >= 0 && doneCol < len(record) {
+			switch strings.ToLower(strings.TrimSpace(record[doneCol])) {
+			case "x", "true", "yes", "1":
+				item.Done = true
+			}
+		}
+		if dueCol >= 0 && dueCol < len(record) && strings.TrimSpace(record[dueCol]) != "" {
+			due, err := time.ParseInLocation(exportDueLayout, strings.TrimSpace(record[dueCol]), time.Local)
+			if err != nil {
+				return nil, fmt.Errorf("invalid due date '%s'", record[dueCol])
+			}
+			item.Due = &due
+		}
+		if assigneeCol >= 0 && assigneeCol < len(record) && strings.TrimSpace(record[assigneeCol]) != "" {
+			item.Assignee = &Assignee{Name: strings.TrimSpace(record[assigneeCol])}
+		}
+		if levelCol >= 0 && levelCol < len(record) {
+			item.Level, _ = strconv.Atoi(strings.TrimSpace(record[levelCol]))
+		}
+		if sectionCol >= 0 && sectionCol < len(record) {
+			item.Section = strings.EqualFold(strings.TrimSpace(record[sectionCol]), "true")
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
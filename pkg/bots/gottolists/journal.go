@@ -0,0 +1,171 @@
+package gottolists
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gvisco/vi.sco/pkg/gotto"
+)
+
+const journalFileExt string = ".history"
+
+// maxJournalLength is the number of operations kept in the journal of a list
+const maxJournalLength int = 50
+
+// errListChanged is returned by undo and redo when the items are not the ones
+// the operation left, e.g. after a change made while the journal was not
+// available: restoring the operation would silently drop that change.
+var errListChanged = errors.New("list changed since")
+
+// operation is a change to a list, stored with the items before and after it
+// so that it can be undone and redone.
+type operation struct {
+	Action string
+	User   string
+	Time   time.Time
+	Before []Item
+	After  []Item
+}
+
+// journal is the history of the changes to a list. The last Undone
+// operations have been undone and can be redone, until a new change drops
+// them.
+type journal struct {
+	Operations []operation
+	Undone     int
+}
+
+func (list *List) journalPath() string {
+	return strings.TrimSuffix(list.filePath, listFileExt) + journalFileExt
+}
+
+func (list *List) loadJournal() (*journal, error) {
+	j := &journal{}
+	data, err := ioutil.ReadFile(list.journalPath())
+	if os.IsNotExist(err) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, j); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+func (list *List) saveJournal(j *journal) error {
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(list.journalPath(), data, 0644)
+}
+
+// record appends to the journal the change from the items before the last
+// save to the current ones.
+func (list *List) record(action string, user string, before []Item) error {
+	j, err := list.loadJournal()
+	if err != nil {
+		return err
+	}
+	j.Operations = append(j.Operations[:len(j.Operations)-j.Undone], operation{
+		Action: action,
+		User:   user,
+		Time:   time.Now(),
+		Before: before,
+		After:  list.saved,
+	})
+	j.Undone = 0
+	if len(j.Operations) > maxJournalLength {
+		j.Operations = j.Operations[len(j.Operations)-maxJournalLength:]
+	}
+	return list.saveJournal(j)
+}
+
+// undo restores the items before the last operation not undone yet, redo
+// the items after the first undone one. They return the restored operation,
+// or nil if there is nothing to undo or redo, and errListChanged with the
+// operation if the current items are not the ones it left.
+func (list *List) undo() (*operation, error) {
+	return list.travel(func(j *journal) (*operation, []Item, []Item) {
+		if j.Undone >= len(j.Operations) {
+			return nil, nil, nil
+		}
+		j.Undone++
+		op := &j.Operations[len(j.Operations)-j.Undone]
+		return op, op.After, op.Before
+	})
+}
+
+func (list *List) redo() (*operation, error) {
+	return list.travel(func(j *journal) (*operation, []Item, []Item) {
+		if j.Undone == 0 {
+			return nil, nil, nil
+		}
+		op := &j.Operations[len(j.Operations)-j.Undone]
+		j.Undone--
+		return op, op.Before, op.After
+	})
+}
+
+// travel moves along the journal by a step, which returns the operation, the
+// items expected before the step and the ones restored.
+func (list *List) travel(step func(*journal) (*operation, []Item, []Item)) (*operation, error) {
+	j, err := list.loadJournal()
+	if err != nil {
+		return nil, err
+	}
+	op, expected, items := step(j)
+	if op == nil {
+		return nil, nil
+	}
+	if !sameItems(list.items, expected) {
+		return op, errListChanged
+	}
+	previous := list.items
+	list.items = append([]Item{}, items...)
+	list.normalize()
+	if err := list.saveToFile(); err != nil {
+		list.items = previous
+		return nil, err
+	}
+	return op, list.saveJournal(j)
+}
+
+// sameItems compares two snapshots of a list as they are stored.
+func sameItems(a []Item, b []Item) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	if len(a) == 0 {
+		return true
+	}
+	da, errA := json.Marshal(a)
+	db, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(da, db)
+}
+
+// history renders the last n operations, most recent first.
+func (list *List) history(loc gotto.Locale, n int) string {
+	j, err := list.loadJournal()
+	if err != nil || len(j.Operations) == 0 {
+		return loc.Sprintf("No changes recorded for list '%s'", list.name)
+	}
+	var b strings.Builder
+	b.WriteString(loc.Sprintf("--- %s: history ---", list.name))
+	for i := len(j.Operations) - 1; i >= 0 && i >= len(j.Operations)-n; i-- {
+		op := j.Operations[i]
+		fmt.Fprintf(&b, "\n%s %s: %s", op.Time.Format("2006-01-02 15:04"), op.User, op.Action)
+		if i >= len(j.Operations)-j.Undone {
+			b.WriteString(" " + loc.Sprintf("(undone)"))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,328 @@
+package gottolists
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gvisco/vi.sco/pkg/gotto"
+	"github.com/gvisco/vi.sco/pkg/quantity"
+)
+
+// listFileHeader marks the files written in the current format, where the
+// header line is followed by a JSON document. Files without it are read as
+// one unchecked item per line, as written by older versions.
+const listFileHeader string = "#gottolists 2"
+
+const listFileExt string = ".list"
+
+const uncheckedMark string = "☐"
+const checkedMark string = "☑"
+
+const dueMark string = "⏰"
+
+type Item struct {
+	Text     string     `json:"text"`
+	Done     bool       `json:"done,omitempty"`
+	Due      *time.Time `json:"due,omitempty"`
+	Assignee *Assignee  `json:"assignee,omitempty"`
+	// Level is the indentation of the item, see sections.go
+	Level int `json:"level,omitempty"`
+	// Section items are headers, which cannot be checked
+	Section bool `json:"section,omitempty"`
+}
+
+func (item Item) String() string {
+	if item.Section {
+		return sectionMark + " " + item.Text
+	}
+	mark := uncheckedMark
+	if item.Done {
+		mark = checkedMark
+	}
+	s := mark + " " + item.Text
+	if item.Due != nil {
+		s += fmt.Sprintf(" %s %s", dueMark, item.Due.Format(dueLayout))
+	}
+	if item.Assignee != nil {
+		s += fmt.Sprintf(" %s %s", assigneeMark, item.Assignee.Name)
+	}
+	return s
+}
+
+// listDocument is the content of a list file after the header line.
+type listDocument struct {
+	Name     string `json:"name,omitempty"`
+	Items    []Item `json:"items"`
+	Archived bool   `json:"archived,omitempty"`
+	// Roles, Everyone and Locked control the access, see access.go
+	Roles    map[int]role `json:"roles,omitempty"`
+	Everyone role         `json:"everyone,omitempty"`
+	Locked   bool         `json:"locked,omitempty"`
+}
+
+// List is identified by the slug of its display name (see slugify), which is
+// also the name of its file.
+type List struct {
+	id       string
+	name     string
+	filePath string
+	items    []Item
+	// saved is a copy of the items last read from or written to the file
+	saved []Item
+	// modTime and size identify the version of the file last read or written
+	modTime time.Time
+	size    int64
+	// token is set if the list is shared, linked if it belongs to another chat
+	token  string
+	linked bool
+	// archived lists are hidden from /list all, see archive.go
+	archived bool
+	// roles by user id, the default role and the lock, see access.go
+	roles    map[int]role
+	everyone role
+	locked   bool
+}
+
+func (list *List) loadFromFile() error {
+	data, err := ioutil.ReadFile(list.filePath)
+	if err != nil {
+		return err
+	}
+
+	if bytes.HasPrefix(data, []byte(listFileHeader+"\n")) {
+		doc := listDocument{}
+		if err := json.Unmarshal(data[len(listFileHeader)+1:], &doc); err != nil {
+			return err
+		}
+		if doc.Name != "" {
+			list.name = doc.Name
+		}
+		list.items = doc.Items
+		list.archived = doc.Archived
+		list.roles, list.everyone, list.locked = doc.Roles, doc.Everyone, doc.Locked
+		list.saved = append([]Item{}, list.items...)
+		return list.stat()
+	}
+
+	var items []Item
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		items = append(items, Item{Text: scanner.Text()})
+	}
+	list.items = items
+	list.saved = append([]Item{}, list.items...)
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return list.stat()
+}
+
+func (list *List) saveToFile() error {
+	doc := listDocument{
+		Name:     list.name,
+		Items:    list.items,
+		Archived: list.archived,
+		Roles:    list.roles,
+		Everyone: list.everyone,
+		Locked:   list.locked,
+	}
+	if doc.Items == nil {
+		doc.Items = []Item{}
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	content := listFileHeader + "\n" + string(data) + "\n"
+	if err := ioutil.WriteFile(list.filePath, []byte(content), 0644); err != nil {
+		return err
+	}
+	list.saved = append([]Item{}, list.items...)
+	return list.stat()
+}
+
+func (list *List) stat() error {
+	info, err := os.Stat(list.filePath)
+	if err != nil {
+		return err
+	}
+	list.modTime, list.size = info.ModTime(), info.Size()
+	return nil
+}
+
+// refresh reloads the list if its file was changed by someone else.
+func (list *List) refresh() error {
+	info, err := os.Stat(list.filePath)
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(list.modTime) && info.Size() == list.size {
+		return nil
+	}
+	return list.loadFromFile()
+}
+
+func (list *List) addItem(item Item) {
+	list.items = append(list.items, item)
+}
+
+func (list *List) insert(item Item, index int) {
+	list.items = append(list.items[:index], append([]Item{item}, list.items[index:]...)...)
+}
+
+func (list *List) remove(index int) {
+	list.items = append(list.items[:index], list.items[index+1:]...)
+}
+
+func (list *List) move(srcIndex int, dstIndex int) {
+	item := list.items[srcIndex]
+	list.remove(srcIndex)
+	list.insert(item, dstIndex)
+}
+
+// merge adds the quantity and the price of an item to the unchecked item of
+// the same product, e.g. "milk €0.60" and "2x milk €1.20" make
+// "3x milk €1.80", and returns whether there was one. Items without a
+// quantity or a price are never merged, and the item is appended to the
+// bottom of the list: only the items of the last section at its level, with
+// the same due date and assignee, are candidates.
+func (list *List) merge(item Item) bool {
+	q := quantity.Parse(item.Text)
+	if !q.Explicit() {
+		return false
+	}
+	for idx := len(list.items) - 1; idx >= 0; idx-- {
+		existing := &list.items[idx]
+		if existing.Section {
+			break
+		}
+		if existing.Done || existing.Level != item.Level || !sameDue(existing.Due, item.Due) || !sameAssignee(existing.Assignee, item.Assignee) {
+			continue
+		}
+		if merged, ok := quantity.Parse(existing.Text).Merge(q); ok {
+			existing.Text = merged.String()
+			return true
+		}
+	}
+	return false
+}
+
+func sameDue(a *time.Time, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func sameAssignee(a *Assignee, b *Assignee) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// total sums the quantities and the prices of the items, sections excluded.
+func (list *List) total() quantity.Total {
+	total := quantity.Total{}
+	for _, item := range list.items {
+		if !item.Section {
+			total.Add(quantity.Parse(item.Text))
+		}
+	}
+	return total
+}
+
+// removeRange removes the items from first to last, both included.
+func (list *List) removeRange(first int, last int) {
+	list.items = append(list.items[:first], list.items[last+1:]...)
+}
+
+// moveRange moves the items from first to last, both included, so that the
+// first of them ends up at position dstIndex.
+func (list *List) moveRange(first int, last int, dstIndex int) {
+	block := append([]Item{}, list.items[first:last+1]...)
+	list.removeRange(first, last)
+	rest := append([]Item{}, list.items[dstIndex:]...)
+	list.items = append(append(list.items[:dstIndex], block...), rest...)
+}
+
+// dedup removes the items whose text, ignoring case and spaces, repeats the
+// one of a previous item, and returns how many they were. Sections are kept.
+func (list *List) dedup() int {
+	seen := make(map[string]bool)
+	kept := []Item{}
+	for _, item := range list.items {
+		key := strings.ToLower(strings.Join(strings.Fields(item.Text), " "))
+		if !item.Section {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		kept = append(kept, item)
+	}
+	removed := len(list.items) - len(kept)
+	list.items = kept
+	return removed
+}
+
+// reverse reverses the order of the items within each section, keeping the
+// children below their parents.
+func (list *List) reverse() {
+	list.reorder(func(units [][]Item) {
+		for i, j := 0, len(units)-1; i < j; i, j = i+1, j-1 {
+			units[i], units[j] = units[j], units[i]
+		}
+	})
+}
+
+// clearDone removes the checked items and returns how many they were.
+func (list *List) clearDone() int {
+	kept := []Item{}
+	for _, item := range list.items {
+		if !item.Done || item.Section {
+			kept = append(kept, item)
+		}
+	}
+	removed := len(list.items) - len(kept)
+	list.items = kept
+	return removed
+}
+
+func (list *List) render() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s ---", list.name)
+	list.renderItems(&b, 0, len(list.items))
+	return b.String()
+}
+
+// renderFiltered renders the items containing the given text, ignoring
+// case, with their positions in the whole list. At most a page of them is
+// shown, if size is not zero.
+func (list *List) renderFiltered(loc gotto.Locale, filter string, size int) string {
+	var b strings.Builder
+	b.WriteString(loc.Sprintf("--- %s (filter: %s) ---", list.name, filter))
+	found, shown := 0, 0
+	for idx, item := range list.items {
+		if strings.Contains(strings.ToLower(item.Text), strings.ToLower(filter)) {
+			found++
+			if size > 0 && shown == size {
+				continue
+			}
+			list.renderItems(&b, idx, idx+1)
+			shown++
+		}
+	}
+	if shown < found {
+		b.WriteString("\n" + loc.Sprintf("…and %d more, write a longer filter to see them", found-shown))
+	}
+	b.WriteString("\n" + loc.Sprintf("%d of %d items shown. Write `/filter` to show all", shown, len(list.items)))
+	return b.String()
+}
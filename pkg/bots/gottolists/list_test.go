@@ -0,0 +1,40 @@
+package gottolists
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	monday := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	tuesday := monday.Add(24 * time.Hour)
+	tests := []struct {
+		name  string
+		items []Item
+		item  Item
+		want  string
+		idx   int
+	}{
+		{"same product", []Item{{Text: "milk €0.60"}}, Item{Text: "2x milk €1.20"}, "3x milk €1.80", 0},
+		{"no quantity", []Item{{Text: "milk"}}, Item{Text: "milk"}, "", -1},
+		{"bare number", []Item{{Text: "10 Downing Street"}}, Item{Text: "2 Downing Street"}, "", -1},
+		{"checked", []Item{{Text: "milk", Done: true}}, Item{Text: "2x milk"}, "", -1},
+		{"other section", []Item{{Text: "milk"}, {Text: "Bakery", Section: true}}, Item{Text: "2x milk"}, "", -1},
+		{"last section", []Item{{Text: "Dairy", Section: true}, {Text: "milk"}}, Item{Text: "2x milk"}, "3x milk", 1},
+		{"child", []Item{{Text: "cake"}, {Text: "milk", Level: 1}}, Item{Text: "2x milk"}, "", -1},
+		{"same due", []Item{{Text: "milk", Due: &monday}}, Item{Text: "2x milk", Due: &monday}, "3x milk", 0},
+		{"other due", []Item{{Text: "milk", Due: &monday}}, Item{Text: "2x milk", Due: &tuesday}, "", -1},
+		{"assigned", []Item{{Text: "milk", Assignee: &Assignee{Name: "John"}}}, Item{Text: "2x milk"}, "", -1},
+	}
+	for _, tt := range tests {
+		list := &List{items: append([]Item{}, tt.items...)}
+		merged := list.merge(tt.item)
+		if merged != (tt.idx >= 0) {
+			t.Errorf("%s: merge = %v, want %v", tt.name, merged, tt.idx >= 0)
+			continue
+		}
+		if merged && list.items[tt.idx].Text != tt.want {
+			t.Errorf("%s: merged into %q, want %q", tt.name, list.items[tt.idx].Text, tt.want)
+		}
+	}
+}
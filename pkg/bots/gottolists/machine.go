@@ -0,0 +1,1017 @@
+package gottolists
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gvisco/vi.sco/pkg/gotto"
+	"github.com/gvisco/vi.sco/pkg/gotto/fsm"
+)
+
+// rangeArg matches a position or a range of positions, e.g. "3-7"
+const rangeArg string = `(\d+(?:-\d+)?)`
+
+var reListView *regexp.Regexp = regexp.MustCompile(`^/list view (.+)$`)
+var reNewList *regexp.Regexp = regexp.MustCompile(`^/list new (.+)$`)
+var reDelList *regexp.Regexp = regexp.MustCompile(`^/list del (.+)$`)
+var reEditList *regexp.Regexp = regexp.MustCompile(`^/list edit (.+)$`)
+var reListAdd *regexp.Regexp = regexp.MustCompile(`(?s)^/list add ` + nameArg + ` (.+)$`)
+var reListRemove *regexp.Regexp = regexp.MustCompile(`^/list rm ` + nameArg + ` ` + rangeArg + `$`)
+var reListCheck *regexp.Regexp = regexp.MustCompile(`^/list check ` + nameArg + ` (\d+)$`)
+var reListUncheck *regexp.Regexp = regexp.MustCompile(`^/list uncheck ` + nameArg + ` (\d+)$`)
+var reImportList *regexp.Regexp = regexp.MustCompile(`^/list import (.+)$`)
+var reExportList *regexp.Regexp = regexp.MustCompile(`^/list export (.+?)(?: (txt|md|csv|json))?$`)
+var reListUndo *regexp.Regexp = regexp.MustCompile(`^/list undo (.+)$`)
+var reListHistory *regexp.Regexp = regexp.MustCompile(`^/list history (.+)$`)
+var reShareList *regexp.Regexp = regexp.MustCompile(`^/list share (.+)$`)
+var reUnshareList *regexp.Regexp = regexp.MustCompile(`^/list unshare (.+)$`)
+var reJoinList *regexp.Regexp = regexp.MustCompile(`^/list join ([0-9A-Fa-f]+)$`)
+var rePinList *regexp.Regexp = regexp.MustCompile(`^/list pin (.+)$`)
+var reUnpinList *regexp.Regexp = regexp.MustCompile(`^/list unpin (.+)$`)
+var reTemplateSave *regexp.Regexp = regexp.MustCompile(`^/list template save (.+)$`)
+var reTemplateDel *regexp.Regexp = regexp.MustCompile(`^/list template del (.+)$`)
+var reNewFromTemplate *regexp.Regexp = regexp.MustCompile(`^/list new ` + nameArg + ` from (.+)$`)
+var reListRepeat *regexp.Regexp = regexp.MustCompile(`^/list repeat ` + nameArg + ` (.+?)(?: from (.+))?$`)
+var reListRestore *regexp.Regexp = regexp.MustCompile(`^/list restore (.+)$`)
+var reListArchive *regexp.Regexp = regexp.MustCompile(`^/list archive (.+)$`)
+var reListUnarchive *regexp.Regexp = regexp.MustCompile(`^/list unarchive (.+)$`)
+var reListLock *regexp.Regexp = regexp.MustCompile(`^/list lock (.+)$`)
+var reListUnlock *regexp.Regexp = regexp.MustCompile(`^/list unlock (.+)$`)
+var reListRole *regexp.Regexp = regexp.MustCompile(`^/list role ` + nameArg + ` (.+) (owner|editor|viewer|none)$`)
+var reListRoles *regexp.Regexp = regexp.MustCompile(`^/list roles (.+)$`)
+var reListFind *regexp.Regexp = regexp.MustCompile(`^/list find (.+)$`)
+var reListRename *regexp.Regexp = regexp.MustCompile(`^/list rename ` + nameArg + ` (.+)$`)
+var reUnrecognizedList *regexp.Regexp = regexp.MustCompile(`^/list(.+)$`)
+var reEditAppend *regexp.Regexp = regexp.MustCompile(`(?s)^/append (.+)$`)
+var reEditRemomve *regexp.Regexp = regexp.MustCompile(`^/rm ` + rangeArg + `$`)
+var reEditAdd *regexp.Regexp = regexp.MustCompile(`^/add (\d+) (.+)$`)
+var reEditMove *regexp.Regexp = regexp.MustCompile(`^/mv ` + rangeArg + ` (\d+)$`)
+var reEditEdit *regexp.Regexp = regexp.MustCompile(`^/edit (\d+) (.+)$`)
+var reEditCheck *regexp.Regexp = regexp.MustCompile(`^/check (\d+)$`)
+var reEditUncheck *regexp.Regexp = regexp.MustCompile(`^/uncheck (\d+)$`)
+var reEditSort *regexp.Regexp = regexp.MustCompile(`^/sort(?: (asc|desc|done|due))?$`)
+var reEditFilter *regexp.Regexp = regexp.MustCompile(`^/filter(?: (.+))?$`)
+var reEditPage *regexp.Regexp = regexp.MustCompile(`^/page (\d+)$`)
+var rePagedView *regexp.Regexp = regexp.MustCompile(`^(.+) (\d+)$`)
+var reEditIndent *regexp.Regexp = regexp.MustCompile(`^/indent ` + rangeArg + `$`)
+var reEditOutdent *regexp.Regexp = regexp.MustCompile(`^/outdent ` + rangeArg + `$`)
+var reEditSection *regexp.Regexp = regexp.MustCompile(`^/section (.+)$`)
+var reEditAssign *regexp.Regexp = regexp.MustCompile(`^/assign (\d+) (.+)$`)
+var reEditUnassign *regexp.Regexp = regexp.MustCompile(`^/unassign (\d+)$`)
+
+type state int
+
+const (
+	waiting state = iota
+	help
+	listAll
+	viewList
+	newList
+	newInput
+	newDone
+	deleteListConfirm
+	deleteListConfirmInput
+	deleteListDone
+	editList
+	editInput
+	editAppend
+	editRemove
+	editAdd
+	editMove
+	editEdit
+	editInvalid
+	editDone
+	editHelp
+	editCheck
+	editUncheck
+	editClearDone
+	listAdd
+	listRemove
+	listCheck
+	listUncheck
+	renameList
+	importList
+	importInput
+	importDone
+	exportList
+	listUndo
+	listHistory
+	editUndo
+	editRedo
+	shareList
+	unshareList
+	joinList
+	pinList
+	unpinList
+	listDue
+	templateSave
+	templateDel
+	listTemplates
+	newFromTemplate
+	listRepeat
+	listRepeats
+	editAssign
+	editUnassign
+	listMine
+	listFind
+	editSort
+	editDedup
+	editReverse
+	editShuffle
+	editFilter
+	editIndent
+	editOutdent
+	editSection
+	listTrash
+	listRestore
+	listArchive
+	listUnarchive
+	listArchived
+	listLock
+	listUnlock
+	listRole
+	listRoles
+	editPage
+	unknownCommand
+)
+
+func (s state) String() string {
+	switch s {
+	case waiting:
+		return "Waiting"
+	case help:
+		return "Help"
+	case listAll:
+		return "ListAll"
+	case viewList:
+		return "ViewList"
+	case newList:
+		return "NewList"
+	case newInput:
+		return "NewInput"
+	case newDone:
+		return "NewDone"
+	case deleteListConfirmInput:
+		return "DeleteListConfirmInput"
+	case deleteListConfirm:
+		return "DeleteListConfirm"
+	case deleteListDone:
+		return "DeleteListDone"
+	case editList:
+		return "EditList"
+	case editInput:
+		return "EditInput"
+	case editAppend:
+		return "EditAppend"
+	case editRemove:
+		return "EditRemove"
+	case editAdd:
+		return "EditAdd"
+	case editMove:
+		return "EditMove"
+	case editEdit:
+		return "EditEdit"
+	case editInvalid:
+		return "EditInvalid"
+	case editDone:
+		return "EditDone"
+	case editHelp:
+		return "EditHelp"
+	case editCheck:
+		return "EditCheck"
+	case editUncheck:
+		return "EditUncheck"
+	case editClearDone:
+		return "EditClearDone"
+	case listAdd:
+		return "ListAdd"
+	case listRemove:
+		return "ListRemove"
+	case listCheck:
+		return "ListCheck"
+	case listUncheck:
+		return "ListUncheck"
+	case renameList:
+		return "RenameList"
+	case importList:
+		return "ImportList"
+	case importInput:
+		return "ImportInput"
+	case importDone:
+		return "ImportDone"
+	case exportList:
+		return "ExportList"
+	case listUndo:
+		return "ListUndo"
+	case listHistory:
+		return "ListHistory"
+	case editUndo:
+		return "EditUndo"
+	case editRedo:
+		return "EditRedo"
+	case shareList:
+		return "ShareList"
+	case unshareList:
+		return "UnshareList"
+	case joinList:
+		return "JoinList"
+	case pinList:
+		return "PinList"
+	case unpinList:
+		return "UnpinList"
+	case listDue:
+		return "ListDue"
+	case templateSave:
+		return "TemplateSave"
+	case templateDel:
+		return "TemplateDel"
+	case listTemplates:
+		return "ListTemplates"
+	case newFromTemplate:
+		return "NewFromTemplate"
+	case listRepeat:
+		return "ListRepeat"
+	case listRepeats:
+		return "ListRepeats"
+	case editAssign:
+		return "EditAssign"
+	case editUnassign:
+		return "EditUnassign"
+	case listMine:
+		return "ListMine"
+	case listFind:
+		return "ListFind"
+	case editSort:
+		return "EditSort"
+	case editDedup:
+		return "EditDedup"
+	case editReverse:
+		return "EditReverse"
+	case editShuffle:
+		return "EditShuffle"
+	case editFilter:
+		return "EditFilter"
+	case editIndent:
+		return "EditIndent"
+	case editOutdent:
+		return "EditOutdent"
+	case editSection:
+		return "EditSection"
+	case listTrash:
+		return "ListTrash"
+	case listRestore:
+		return "ListRestore"
+	case listArchive:
+		return "ListArchive"
+	case listUnarchive:
+		return "ListUnarchive"
+	case listArchived:
+		return "ListArchived"
+	case listLock:
+		return "ListLock"
+	case listUnlock:
+		return "ListUnlock"
+	case listRole:
+		return "ListRole"
+	case listRoles:
+		return "ListRoles"
+	case editPage:
+		return "EditPage"
+	case unknownCommand:
+		return "UnknownCommand"
+	default:
+		return fmt.Sprintf("%d", int(s))
+	}
+}
+
+// historyLength is the number of changes printed by /list history
+const historyLength int = 10
+
+// listMachine drives every ListBot conversation. Actions and guards receive
+// the *ListBot owning the instance as context.
+var listMachine *fsm.Machine = newListMachine()
+
+// Machine returns the state machine of ListBot, e.g. to draw its diagram.
+func Machine() *fsm.Machine {
+	return listMachine
+}
+
+func newListMachine() *fsm.Machine {
+	m := fsm.New(waiting)
+
+	m.AddState(waiting, nil, nil).
+		Add(&fsm.Transition{From: waiting, To: waiting, Label: "<denied>", Guard: denied, Action: act((*ListBot).deny)}).
+		On(waiting, "/list help", is("/list help"), help).
+		On(waiting, "/list all", is("/list all"), listAll).
+		On(waiting, "/list view <name> [page]", matches(reListView), viewList).
+		On(waiting, "/list new <name> from <template>", hasTemplate, newFromTemplate).
+		On(waiting, "/list new <name>", matches(reNewList), newList).
+		On(waiting, "/list del <name>", matches(reDelList), deleteListConfirm).
+		On(waiting, "/list edit <name>", matches(reEditList), editList).
+		On(waiting, "/list add <name> <item>", matches(reListAdd), listAdd).
+		On(waiting, "/list rm <name> <position>", matches(reListRemove), listRemove).
+		On(waiting, "/list check <name> <position>", matches(reListCheck), listCheck).
+		On(waiting, "/list uncheck <name> <position>", matches(reListUncheck), listUncheck).
+		On(waiting, "/list rename <old> <new>", matches(reListRename), renameList).
+		On(waiting, "/list import <name>", matches(reImportList), importList).
+		On(waiting, "/list export <name> [format]", matches(reExportList), exportList).
+		On(waiting, "/list undo <name>", matches(reListUndo), listUndo).
+		On(waiting, "/list history <name>", matches(reListHistory), listHistory).
+		On(waiting, "/list share <name>", matches(reShareList), shareList).
+		On(waiting, "/list unshare <name>", matches(reUnshareList), unshareList).
+		On(waiting, "/list join <token>", matches(reJoinList), joinList).
+		On(waiting, "/list due", is("/list due"), listDue).
+		On(waiting, "/list mine", is("/list mine"), listMine).
+		On(waiting, "/list find <query>", matches(reListFind), listFind).
+		On(waiting, "/list template save <name>", matches(reTemplateSave), templateSave).
+		On(waiting, "/list template del <name>", matches(reTemplateDel), templateDel).
+		On(waiting, "/list templates", is("/list templates"), listTemplates).
+		On(waiting, "/list repeat <name> <schedule> [from <template>]", matches(reListRepeat), listRepeat).
+		On(waiting, "/list repeats", is("/list repeats"), listRepeats).
+		On(waiting, "/list trash", is("/list trash"), listTrash).
+		On(waiting, "/list restore <name>", matches(reListRestore), listRestore).
+		On(waiting, "/list archive <name>", matches(reListArchive), listArchive).
+		On(waiting, "/list unarchive <name>", matches(reListUnarchive), listUnarchive).
+		On(waiting, "/list archived", is("/list archived"), listArchived).
+		On(waiting, "/list lock <name>", matches(reListLock), listLock).
+		On(waiting, "/list unlock <name>", matches(reListUnlock), listUnlock).
+		On(waiting, "/list role <name> <user> <role>", matches(reListRole), listRole).
+		On(waiting, "/list roles <name>", matches(reListRoles), listRoles).
+		On(waiting, "/list pin <name>", matches(rePinList), pinList).
+		On(waiting, "/list unpin <name>", matches(reUnpinList), unpinList).
+		On(waiting, "/list <unrecognized>", matches(reUnrecognizedList), unknownCommand)
+
+	m.AddState(help, reply(helpString), nil).
+		Then(help, "", nil, waiting)
+
+	m.AddState(unknownCommand, act((*ListBot).unknownCommand), nil).
+		Then(unknownCommand, "", nil, waiting)
+
+	m.AddState(listAll, act((*ListBot).listAll), nil).
+		Then(listAll, "", nil, waiting)
+
+	m.AddState(viewList, act((*ListBot).viewList), nil).
+		Then(viewList, "", nil, waiting)
+
+	for _, op := range []struct {
+		state  state
+		action func(*ListBot, string) string
+	}{
+		{listAdd, (*ListBot).listAdd},
+		{listRemove, (*ListBot).listRemove},
+		{listCheck, (*ListBot).listCheck},
+		{listUncheck, (*ListBot).listUncheck},
+		{listUndo, (*ListBot).listUndo},
+		{listHistory, (*ListBot).listHistory},
+		{shareList, (*ListBot).share},
+		{unshareList, (*ListBot).unshare},
+		{joinList, (*ListBot).join},
+		{pinList, (*ListBot).pin},
+		{unpinList, (*ListBot).unpin},
+		{listDue, (*ListBot).listDue},
+		{listMine, (*ListBot).listMine},
+		{listFind, (*ListBot).findItems},
+		{templateSave, (*ListBot).saveTemplate},
+		{templateDel, (*ListBot).deleteTemplate},
+		{listTemplates, (*ListBot).listTemplates},
+		{newFromTemplate, (*ListBot).newFromTemplate},
+		{listRepeat, (*ListBot).repeat},
+		{listRepeats, (*ListBot).listRepeats},
+		{listTrash, (*ListBot).listTrash},
+		{listRestore, (*ListBot).restore},
+		{listArchive, (*ListBot).archive},
+		{listUnarchive, (*ListBot).unarchive},
+		{listArchived, (*ListBot).listArchived},
+		{listLock, (*ListBot).lock},
+		{listUnlock, (*ListBot).unlock},
+		{listRole, (*ListBot).setRole},
+		{listRoles, (*ListBot).listRoles},
+	} {
+		m.AddState(op.state, act(op.action), nil).
+			Then(op.state, "", nil, waiting)
+	}
+
+	m.AddState(exportList, act((*ListBot).exportList), nil).
+		Then(exportList, "", nil, waiting)
+
+	m.AddState(renameList, act((*ListBot).renameList), nil).
+		Then(renameList, "", nil, waiting)
+
+	m.AddState(importList, act((*ListBot).importList), nil).
+		Then(importList, "error", failed, waiting).
+		Then(importList, "", nil, importInput)
+
+	m.AddState(importInput, nil, nil).
+		Add(&fsm.Transition{From: importInput, To: waiting, Label: "/end", Guard: is("/end"), Action: reply("Import cancelled")}).
+		Add(&fsm.Transition{From: importInput, To: importInput, Label: "<denied>", Guard: lacks(editor, true), Action: act((*ListBot).deny)}).
+		On(importInput, "<document>", hasDocument, importDone).
+		On(importInput, "*", nil, importDone)
+
+	m.AddState(importDone, act((*ListBot).importDone), nil).
+		Then(importDone, "", nil, waiting)
+
+	m.AddState(newList, act((*ListBot).newList), nil).
+		Then(newList, "error", failed, waiting).
+		Then(newList, "", nil, newInput)
+
+	m.AddState(newInput, nil, nil).
+		On(newInput, "/end", is("/end"), newDone).
+		Add(&fsm.Transition{From: newInput, To: newInput, Label: "<denied>", Guard: lacks(editor, true), Action: act((*ListBot).deny)}).
+		Add(&fsm.Transition{From: newInput, To: newInput, Label: "*", Action: act((*ListBot).newItem)}).
+		Then(newInput, "error", failed, waiting)
+
+	m.AddState(newDone, act((*ListBot).newDone), nil).
+		Then(newDone, "", nil, waiting)
+
+	m.AddState(deleteListConfirm, act((*ListBot).deleteListConfirm), nil).
+		Then(deleteListConfirm, "error", failed, waiting).
+		Then(deleteListConfirm, "", nil, deleteListConfirmInput)
+
+	m.AddState(deleteListConfirmInput, reply("Please reply 'yes' or 'no'"), nil).
+		On(deleteListConfirmInput, "no", answerNo, waiting).
+		Add(&fsm.Transition{From: deleteListConfirmInput, To: deleteListConfirmInput, Label: "<denied>", Guard: all(answerYes, lacksToDelete), Action: act((*ListBot).deny)}).
+		On(deleteListConfirmInput, "yes", answerYes, deleteListDone).
+		On(deleteListConfirmInput, "*", nil, deleteListConfirmInput)
+
+	m.AddState(deleteListDone, act((*ListBot).deleteListDone), nil).
+		Then(deleteListDone, "", nil, waiting)
+
+	m.AddState(editList, act((*ListBot).editList), nil).
+		Then(editList, "error", failed, waiting).
+		Then(editList, "", nil, editInput)
+
+	m.AddState(editInput, act((*ListBot).editView), nil).
+		On(editInput, "/help", is("/help"), editHelp).
+		On(editInput, "/end", is("/end"), editDone).
+		On(editInput, "/filter [text]", matches(reEditFilter), editFilter).
+		On(editInput, "/page <number>", matches(reEditPage), editPage).
+		Add(&fsm.Transition{From: editInput, To: editInput, Label: "<denied>", Guard: lacks(editor, true), Action: act((*ListBot).deny)}).
+		On(editInput, "/append <item>", matches(reEditAppend), editAppend).
+		On(editInput, "/rm <position>", matches(reEditRemomve), editRemove).
+		On(editInput, "/add <position> <item>", matches(reEditAdd), editAdd).
+		On(editInput, "/mv <from> <to>", matches(reEditMove), editMove).
+		On(editInput, "/edit <position> <item>", matches(reEditEdit), editEdit).
+		On(editInput, "/check <position>", matches(reEditCheck), editCheck).
+		On(editInput, "/uncheck <position>", matches(reEditUncheck), editUncheck).
+		On(editInput, "/clear-done", is("/clear-done"), editClearDone).
+		On(editInput, "/assign <position> <user>", matches(reEditAssign), editAssign).
+		On(editInput, "/unassign <position>", matches(reEditUnassign), editUnassign).
+		On(editInput, "/sort [asc|desc|done|due]", matches(reEditSort), editSort).
+		On(editInput, "/dedup", is("/dedup"), editDedup).
+		On(editInput, "/reverse", is("/reverse"), editReverse).
+		On(editInput, "/shuffle", is("/shuffle"), editShuffle).
+		On(editInput, "/indent <position>", matches(reEditIndent), editIndent).
+		On(editInput, "/outdent <position>", matches(reEditOutdent), editOutdent).
+		On(editInput, "/section <name>", matches(reEditSection), editSection).
+		On(editInput, "/undo", is("/undo"), editUndo).
+		On(editInput, "/redo", is("/redo"), editRedo).
+		On(editInput, "*", nil, editInvalid)
+
+	for _, op := range []struct {
+		state  state
+		action func(*ListBot, string) string
+	}{
+		{editAppend, (*ListBot).editAppend},
+		{editRemove, (*ListBot).editRemove},
+		{editAdd, (*ListBot).editAdd},
+		{editMove, (*ListBot).editMove},
+		{editEdit, (*ListBot).editEdit},
+		{editCheck, (*ListBot).editCheck},
+		{editUncheck, (*ListBot).editUncheck},
+		{editClearDone, (*ListBot).editClearDone},
+		{editUndo, (*ListBot).editUndo},
+		{editRedo, (*ListBot).editRedo},
+		{editAssign, (*ListBot).editAssign},
+		{editUnassign, (*ListBot).editUnassign},
+		{editSort, (*ListBot).editSort},
+		{editDedup, (*ListBot).editDedup},
+		{editReverse, (*ListBot).editReverse},
+		{editShuffle, (*ListBot).editShuffle},
+		{editFilter, (*ListBot).editFilter},
+		{editPage, (*ListBot).editPage},
+		{editIndent, (*ListBot).editIndent},
+		{editOutdent, (*ListBot).editOutdent},
+		{editSection, (*ListBot).editSection},
+	} {
+		m.AddState(op.state, act(op.action), nil).
+			Then(op.state, "error", failed, waiting).
+			Then(op.state, "", nil, editInput)
+	}
+
+	m.AddState(editInvalid, act((*ListBot).invalidEdit), nil).
+		Then(editInvalid, "", nil, editInput)
+
+	m.AddState(editDone, act((*ListBot).editDone), nil).
+		Then(editDone, "", nil, waiting)
+
+	m.AddState(editHelp, reply(editHelpString), nil).
+		Then(editHelp, "", nil, editInput)
+
+	return m.MustValidate()
+}
+
+func is(command string) fsm.Guard {
+	return func(_ interface{}, s string) bool { return s == command }
+}
+
+func matches(re *regexp.Regexp) fsm.Guard {
+	return func(_ interface{}, s string) bool { return re.MatchString(s) }
+}
+
+// answerYes and answerNo guard the answers to a confirmation, in English or
+// in the language of the user.
+func answerYes(ctx interface{}, s string) bool {
+	return ctx.(*ListBot).locale().IsYes(s)
+}
+
+func answerNo(ctx interface{}, s string) bool {
+	return ctx.(*ListBot).locale().IsNo(s)
+}
+
+func hasDocument(ctx interface{}, _ string) bool {
+	return ctx.(*ListBot).document != nil
+}
+
+func failed(ctx interface{}, _ string) bool {
+	return ctx.(*ListBot).failed
+}
+
+func act(f func(*ListBot, string) string) fsm.Action {
+	return func(ctx interface{}, s string) string { return f(ctx.(*ListBot), s) }
+}
+
+// reply answers with a message, translated for the user.
+func reply(text string) fsm.Action {
+	return func(ctx interface{}, _ string) string { return ctx.(*ListBot).tr(text) }
+}
+
+// abort logs err, marks the current interaction as failed and returns the
+// message for the user.
+func (lb *ListBot) abort(action string, lname string, err error) string {
+	lb.failed = true
+	log.Printf("[ERROR ListBot Cannot %s] Workspace {%s} ListName {%s} Error {%s} ", action, lb.workspace, lname, err)
+	return lb.tr("Cannot %s '%s'. An error occurred", lb.tr(action), lname)
+}
+
+// listAll prints the names of the lists, archived ones excluded.
+func (lb *ListBot) listAll(s string) string {
+	result := lb.tr("Your lists:")
+	archived := 0
+	for _, l := range lb.sortedLists() {
+		if l.archived {
+			archived++
+			continue
+		}
+		result = fmt.Sprintf("%s\n- %s", result, l.name)
+	}
+	if archived > 0 {
+		result += "\n" + lb.tr("(%d archived, see /list archived)", archived)
+	}
+	return result
+}
+
+// viewList sends a page of a list, the first one unless the name is
+// followed by a page number.
+func (lb *ListBot) viewList(s string) string {
+	lname := unquote(reListView.FindStringSubmatch(s)[1])
+	l, ok := lb.findList(lname)
+	page := 0
+	if args := rePagedView.FindStringSubmatch(lname); !ok && args != nil {
+		if l, ok = lb.findList(args[1]); ok {
+			page, ok = parsePage(args[2])
+		}
+	}
+	if !ok {
+		return lb.tr("Invalid list name: %s", lname)
+	}
+	if _, err := lb.conversation.Send(l.viewReply(lb.locale(), page, lb.config.PageSize)); err != nil {
+		log.Printf("[ERROR ListBot Cannot send list view] Workspace {%s} ListName {%s} Error {%s} ", lb.workspace, lname, err)
+		return l.renderPage(lb.locale(), page, lb.config.PageSize)
+	}
+	return ""
+}
+
+// oneShot applies an operation to a list from the Waiting state, replying
+// with the updated list, or its changed part.
+func (lb *ListBot) oneShot(lname string, op func(*List) string) string {
+	l, ok := lb.findList(lname)
+	if !ok {
+		return lb.tr("Invalid list name: %s", unquote(lname))
+	}
+	before := append([]Item{}, l.items...)
+	if msg := op(l); msg != "" {
+		return msg
+	}
+	return lb.renderChange(l, before, 0)
+}
+
+func (lb *ListBot) listAdd(s string) string {
+	args := reListAdd.FindStringSubmatch(s)
+	return lb.oneShot(args[1], func(l *List) string { return lb.appendItems(l, args[2]) })
+}
+
+func (lb *ListBot) listRemove(s string) string {
+	args := reListRemove.FindStringSubmatch(s)
+	return lb.oneShot(args[1], func(l *List) string { return lb.removeItem(l, args[2]) })
+}
+
+func (lb *ListBot) listCheck(s string) string {
+	args := reListCheck.FindStringSubmatch(s)
+	return lb.oneShot(args[1], func(l *List) string { return lb.setDone(l, args[2], true) })
+}
+
+func (lb *ListBot) listUncheck(s string) string {
+	args := reListUncheck.FindStringSubmatch(s)
+	return lb.oneShot(args[1], func(l *List) string { return lb.setDone(l, args[2], false) })
+}
+
+// exportList sends a list, or a zip of all the lists with name "all", as a
+// document. A list actually named "all" can be exported quoting its name.
+func (lb *ListBot) exportList(s string) string {
+	args := reExportList.FindStringSubmatch(s)
+	format := args[2]
+	if format == "" {
+		format = defaultExportFormat
+	}
+	var fileName string
+	var content []byte
+	var err error
+	if strings.TrimSpace(args[1]) == "all" {
+		fileName = "lists.zip"
+		content, err = exportAll(lb.sortedLists(), format, time.Now())
+	} else {
+		l, ok := lb.findList(args[1])
+		if !ok {
+			return lb.tr("Invalid list name: %s", unquote(args[1]))
+		}
+		fileName = l.id + "." + format
+		content, err = l.export(format, time.Now())
+	}
+	if err == nil {
+		err = lb.conversation.SendDocument(fileName, content)
+	}
+	if err != nil {
+		log.Printf("[ERROR ListBot Cannot export list] Workspace {%s} File {%s} Error {%s} ", lb.workspace, fileName, err)
+		return lb.tr("Cannot export '%s'. An error occurred", fileName)
+	}
+	return ""
+}
+
+func (lb *ListBot) renameList(s string) string {
+	args := reListRename.FindStringSubmatch(s)
+	l, ok := lb.findList(args[1])
+	if !ok {
+		return lb.tr("Invalid list name: %s", unquote(args[1]))
+	}
+	if l.token != "" {
+		return lb.tr("List '%s' is shared, stop sharing it before renaming it", l.name)
+	}
+	newName := unquote(args[2])
+	id, ok := validName(newName)
+	if !ok {
+		return lb.tr("Invalid list name: %s", newName)
+	}
+	if other, ok := lb.lists[id]; ok && other != l {
+		return lb.tr("A list with name '%s' already exists", other.name)
+	}
+	oldId, oldName, oldPath := l.id, l.name, l.filePath
+	l.id, l.name, l.filePath = id, newName, filepath.Join(lb.workspace, id+listFileExt)
+	if err := l.saveToFile(); err != nil {
+		l.id, l.name, l.filePath = oldId, oldName, oldPath
+		return lb.abort("rename list", oldName, err)
+	}
+	if oldPath != l.filePath {
+		if err := os.Remove(oldPath); err != nil {
+			log.Printf("[ERROR ListBot Cannot remove renamed list file] Workspace {%s} File {%s} Error {%s} ", lb.workspace, oldPath, err)
+		}
+		oldJournal := strings.TrimSuffix(oldPath, listFileExt) + journalFileExt
+		if err := os.Rename(oldJournal, l.journalPath()); err != nil && !os.IsNotExist(err) {
+			log.Printf("[ERROR ListBot Cannot move the history of renamed list] Workspace {%s} File {%s} Error {%s} ", lb.workspace, oldJournal, err)
+		}
+	}
+	delete(lb.lists, oldId)
+	lb.lists[id] = l
+	lb.moveRecurrence(oldId, id)
+	if lb.conversation.Pinned(pinKey(oldId)) {
+		if err := lb.conversation.MovePinned(pinKey(oldId), pinKey(id)); err != nil {
+			log.Printf("[ERROR ListBot Cannot move the pin of renamed list] Workspace {%s} ListName {%s} Error {%s} ", lb.workspace, newName, err)
+		}
+		lb.unwatch(oldPath)
+		lb.watch(l.filePath)
+		lb.listChanged(l)
+	}
+	return lb.tr("List '%s' renamed to '%s'", oldName, newName)
+}
+
+func (lb *ListBot) listUndo(s string) string {
+	lname := reListUndo.FindStringSubmatch(s)[1]
+	l, ok := lb.findList(lname)
+	if !ok {
+		return lb.tr("Invalid list name: %s", unquote(lname))
+	}
+	return lb.undo(l)
+}
+
+func (lb *ListBot) listHistory(s string) string {
+	lname := reListHistory.FindStringSubmatch(s)[1]
+	l, ok := lb.findList(lname)
+	if !ok {
+		return lb.tr("Invalid list name: %s", unquote(lname))
+	}
+	return l.history(lb.locale(), historyLength)
+}
+
+func (lb *ListBot) share(s string) string {
+	lname := reShareList.FindStringSubmatch(s)[1]
+	l, ok := lb.findList(lname)
+	if !ok {
+		return lb.tr("Invalid list name: %s", unquote(lname))
+	}
+	token, err := lb.shareList(l, fmt.Sprint(lb.user.Id))
+	if err != nil {
+		log.Printf("[ERROR ListBot Cannot share list] Workspace {%s} ListName {%s} Error {%s} ", lb.workspace, l.name, err)
+		return lb.tr("Cannot share list '%s': %s", l.name, lb.errorText(err))
+	}
+	return lb.tr("List '%s' is shared. To use it in another chat write there:\n/list join %s", l.name, token)
+}
+
+func (lb *ListBot) unshare(s string) string {
+	lname := reUnshareList.FindStringSubmatch(s)[1]
+	l, ok := lb.findList(lname)
+	if !ok {
+		return lb.tr("Invalid list name: %s", unquote(lname))
+	}
+	if l.token == "" {
+		return lb.tr("List '%s' is not shared", l.name)
+	}
+	if err := lb.unshareList(l, fmt.Sprint(lb.user.Id)); err != nil {
+		log.Printf("[ERROR ListBot Cannot unshare list] Workspace {%s} ListName {%s} Error {%s} ", lb.workspace, l.name, err)
+		return lb.tr("Cannot stop sharing list '%s': %s", l.name, lb.errorText(err))
+	}
+	lb.listRemoved(l, true, notSharedNotice)
+	return lb.tr("List '%s' is not shared anymore", l.name)
+}
+
+func (lb *ListBot) join(s string) string {
+	token := strings.ToUpper(reJoinList.FindStringSubmatch(s)[1])
+	l, err := lb.joinList(token)
+	if err != nil {
+		log.Printf("[ERROR ListBot Cannot join list] Workspace {%s} Token {%s} Error {%s} ", lb.workspace, token, err)
+		return lb.tr("Cannot join the list: %s", lb.errorText(err))
+	}
+	return lb.tr("List '%s' is now available in this chat", l.name)
+}
+
+func (lb *ListBot) newList(s string) string {
+	lname := unquote(reNewList.FindStringSubmatch(s)[1])
+	if l, ok := lb.findList(lname); ok {
+		lb.failed = true
+		return lb.tr("A list with name '%s' already exists", l.name)
+	}
+	list, msg := lb.createList(lname)
+	if list == nil {
+		return msg
+	}
+	lb.currentList = list
+	return lb.tr("I'm listening. Add new items to list '%s'.\nWrite `/end` to complete", lname)
+}
+
+// createList creates an empty list. On failure it returns nil and a message
+// for the user.
+func (lb *ListBot) createList(lname string) (*List, string) {
+	id, ok := validName(lname)
+	if !ok {
+		lb.failed = true
+		return nil, lb.tr("Invalid list name: %s", lname)
+	}
+	list := &List{
+		id:       id,
+		name:     lname,
+		filePath: filepath.Join(lb.workspace, id+listFileExt),
+		items:    []Item{},
+	}
+	if lb.user.Id != 0 {
+		list.roles = map[int]role{lb.user.Id: owner}
+	}
+	err := list.saveToFile()
+	if err != nil {
+		return nil, lb.abort("save list", lname, err)
+	}
+	lb.lists[id] = list
+	return list, ""
+}
+
+func (lb *ListBot) importList(s string) string {
+	lname := unquote(reImportList.FindStringSubmatch(s)[1])
+	list, ok := lb.findList(lname)
+	if !ok {
+		var msg string
+		if list, msg = lb.createList(lname); list == nil {
+			return msg
+		}
+	}
+	lb.currentList = list
+	return lb.tr("Send me the items to add to list '%s', as a message or a .txt, .md or .csv file.\nWrite `/end` to cancel", list.name)
+}
+
+func (lb *ListBot) importDone(s string) string {
+	var items []Item
+	if lb.document != nil {
+		var err error
+		items, err = parseItems(lb.document.name, lb.document.content)
+		if err != nil {
+			log.Printf("[ERROR ListBot Cannot parse document] Workspace {%s} File {%s} Error {%s} ", lb.workspace, lb.document.name, err)
+			return lb.tr("Cannot import '%s': %s", lb.document.name, err)
+		}
+	} else {
+		items = parseTextItems(s)
+	}
+	lb.currentList.items = append(lb.currentList.items, items...)
+	if msg := lb.saveList(lb.currentList); msg != "" {
+		return msg
+	}
+	return lb.tr("Imported %d items into list '%s'", len(items), lb.currentList.name)
+}
+
+func (lb *ListBot) newItem(s string) string {
+	return lb.appendItems(lb.currentList, s)
+}
+
+func (lb *ListBot) newDone(s string) string {
+	return lb.tr("New list '%s' created with %d items", lb.currentList.name, len(lb.currentList.items))
+}
+
+func (lb *ListBot) deleteListConfirm(s string) string {
+	lname := unquote(reDelList.FindStringSubmatch(s)[1])
+	l, ok := lb.findList(lname)
+	if !ok {
+		lb.failed = true
+		return lb.tr("Invalid list name: %s", lname)
+	}
+	lb.currentList = l
+	return lb.tr("Are you sure you want to delete list '%s'?", lb.currentList.name)
+}
+
+func (lb *ListBot) deleteListDone(s string) string {
+	toBeDeleted := lb.currentList
+	if toBeDeleted.linked {
+		// only the owner chat can delete a shared list
+		if err := os.Remove(lb.linkPath(toBeDeleted.id)); err != nil {
+			return lb.abort("remove list", toBeDeleted.name, err)
+		}
+		lb.unpinList(toBeDeleted, &gotto.Reply{Text: lb.tr("List '%s' removed from this chat", toBeDeleted.name)})
+		lb.currentList = nil
+		lb.stopRecurrence(toBeDeleted.id)
+		delete(lb.lists, toBeDeleted.id)
+		return lb.tr("List '%s' removed from this chat", toBeDeleted.name)
+	}
+	if toBeDeleted.token != "" {
+		if err := lb.revokeShare(toBeDeleted); err != nil {
+			return lb.abort("stop sharing list", toBeDeleted.name, err)
+		}
+		lb.listRemoved(toBeDeleted, true, notSharedNotice)
+	}
+	if err := lb.moveToTrash(toBeDeleted); err != nil {
+		return lb.abort("delete list", toBeDeleted.name, err)
+	}
+	lb.listRemoved(toBeDeleted, false, "List '%s' was deleted")
+	lb.stopRecurrence(toBeDeleted.id)
+	lb.currentList = nil
+	delete(lb.lists, toBeDeleted.id)
+	lb.purgeTrash()
+	if lb.config.TrashRetention > 0 {
+		return lb.tr("List '%s' moved to the trash until %s. Restore it with /list restore %s", toBeDeleted.name, time.Now().Add(lb.config.TrashRetention).Format(dueLayout), toBeDeleted.name)
+	}
+	return lb.tr("List '%s' moved to the trash. Restore it with /list restore %s", toBeDeleted.name, toBeDeleted.name)
+}
+
+func (lb *ListBot) editList(s string) string {
+	lname := unquote(reEditList.FindStringSubmatch(s)[1])
+	l, ok := lb.findList(lname)
+	if !ok {
+		lb.failed = true
+		return lb.tr("Invalid list name: %s", lname)
+	}
+	lb.currentList = l
+	lb.filter = ""
+	lb.page, lb.showPage = 0, true
+	return lb.tr("Editing list '%s'.\nWrite `/help` to see the available commands", lb.currentList.name)
+}
+
+// editView shows the items changed by the last command, a page of the list
+// after /page or the matches of the filter.
+func (lb *ListBot) editView(s string) string {
+	if lb.filter != "" {
+		return lb.currentList.renderFiltered(lb.locale(), lb.filter, lb.config.PageSize)
+	}
+	if lb.showPage {
+		lb.showPage = false
+		return lb.currentList.renderPage(lb.locale(), lb.page, lb.config.PageSize)
+	}
+	return lb.renderChange(lb.currentList, lb.before, lb.page)
+}
+
+func (lb *ListBot) editPage(s string) string {
+	page, ok := parsePage(reEditPage.FindStringSubmatch(s)[1])
+	if !ok {
+		return lb.tr("Pages are numbered from 1")
+	}
+	lb.page, lb.showPage = page, true
+	return ""
+}
+
+func (lb *ListBot) editAppend(s string) string {
+	return lb.appendItems(lb.currentList, reEditAppend.FindStringSubmatch(s)[1])
+}
+
+func (lb *ListBot) editRemove(s string) string {
+	return lb.removeItem(lb.currentList, reEditRemomve.FindStringSubmatch(s)[1])
+}
+
+func (lb *ListBot) editAdd(s string) string {
+	args := reEditAdd.FindStringSubmatch(s)
+	return lb.insertItem(lb.currentList, args[1], args[2])
+}
+
+func (lb *ListBot) editMove(s string) string {
+	args := reEditMove.FindStringSubmatch(s)
+	return lb.moveItem(lb.currentList, args[1], args[2])
+}
+
+func (lb *ListBot) editEdit(s string) string {
+	args := reEditEdit.FindStringSubmatch(s)
+	return lb.editItem(lb.currentList, args[1], args[2])
+}
+
+func (lb *ListBot) editCheck(s string) string {
+	return lb.setDone(lb.currentList, reEditCheck.FindStringSubmatch(s)[1], true)
+}
+
+func (lb *ListBot) editUncheck(s string) string {
+	return lb.setDone(lb.currentList, reEditUncheck.FindStringSubmatch(s)[1], false)
+}
+
+func (lb *ListBot) editAssign(s string) string {
+	args := reEditAssign.FindStringSubmatch(s)
+	return lb.assignItem(lb.currentList, args[1], args[2])
+}
+
+func (lb *ListBot) editUnassign(s string) string {
+	return lb.unassignItem(lb.currentList, reEditUnassign.FindStringSubmatch(s)[1])
+}
+
+func (lb *ListBot) editSort(s string) string {
+	return lb.sortItems(lb.currentList, reEditSort.FindStringSubmatch(s)[1])
+}
+
+func (lb *ListBot) editDedup(s string) string {
+	return lb.dedupItems(lb.currentList)
+}
+
+func (lb *ListBot) editReverse(s string) string {
+	return lb.reverseItems(lb.currentList)
+}
+
+func (lb *ListBot) editShuffle(s string) string {
+	return lb.shuffleItems(lb.currentList)
+}
+
+// editFilter only changes the view: until it is cleared, the list is shown
+// without the items not containing the filter text.
+func (lb *ListBot) editFilter(s string) string {
+	lb.filter = strings.TrimSpace(reEditFilter.FindStringSubmatch(s)[1])
+	return ""
+}
+
+func (lb *ListBot) editIndent(s string) string {
+	return lb.indentItems(lb.currentList, reEditIndent.FindStringSubmatch(s)[1])
+}
+
+func (lb *ListBot) editOutdent(s string) string {
+	return lb.outdentItems(lb.currentList, reEditOutdent.FindStringSubmatch(s)[1])
+}
+
+func (lb *ListBot) editSection(s string) string {
+	return lb.addSection(lb.currentList, reEditSection.FindStringSubmatch(s)[1])
+}
+
+func (lb *ListBot) editUndo(s string) string {
+	return lb.undo(lb.currentList)
+}
+
+func (lb *ListBot) editRedo(s string) string {
+	return lb.redo(lb.currentList)
+}
+
+func (lb *ListBot) editClearDone(s string) string {
+	return lb.clearDone(lb.currentList)
+}
+
+func (lb *ListBot) editDone(s string) string {
+	return lb.tr("Edit of list '%s' complete", lb.currentList.name)
+}
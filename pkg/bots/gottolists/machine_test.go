@@ -0,0 +1,36 @@
+package gottolists
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// readmePath is the README of the repository, which draws the state machine
+const readmePath string = "../../../README.md"
+
+func TestMachineIsValid(t *testing.T) {
+	if err := Machine().Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadmeDiagram(t *testing.T) {
+	doc, err := ioutil.ReadFile(readmePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const fence = "```mermaid\n"
+	start := strings.Index(string(doc), fence)
+	if start < 0 {
+		t.Fatalf("no mermaid diagram in %s", readmePath)
+	}
+	block := string(doc[start+len(fence):])
+	end := strings.Index(block, "```")
+	if end < 0 {
+		t.Fatalf("unterminated mermaid diagram in %s", readmePath)
+	}
+	if block[:end] != Machine().Mermaid() {
+		t.Errorf("the diagram in %s is out of date, regenerate it with: go run ./vito fsm-graph -bot gottolists", readmePath)
+	}
+}
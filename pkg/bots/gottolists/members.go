@@ -0,0 +1,155 @@
+package gottolists
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gvisco/vi.sco/pkg/gotto"
+)
+
+// membersFileName stores the users seen in the chat, so that items can be
+// assigned to them by username or first name.
+const membersFileName string = "listbot.members"
+
+const assigneeMark string = "👤"
+
+// Assignee is the user an item is assigned to. Id is zero for users who
+// were never seen in the chat, who are matched by Name ("@username") once
+// they show up.
+type Assignee struct {
+	Id   int    `json:"id,omitempty"`
+	Name string `json:"name"`
+}
+
+func (bot *ListBot) loadMembers() {
+	bot.members = make(map[int]gotto.User)
+	data, err := ioutil.ReadFile(filepath.Join(bot.workspace, membersFileName))
+	if os.IsNotExist(err) {
+		return
+	}
+	members := []gotto.User{}
+	if err == nil {
+		err = json.Unmarshal(data, &members)
+	}
+	if err != nil {
+		log.Printf("[ERROR ListBot cannot read members] Workspace {%s} Error {%s}", bot.workspace, err)
+		return
+	}
+	for _, m := range members {
+		bot.members[m.Id] = m
+	}
+}
+
+func (bot *ListBot) saveMembers() error {
+	members := make([]gotto.User, 0, len(bot.members))
+	for _, m := range bot.members {
+		members = append(members, m)
+	}
+	data, err := json.MarshalIndent(members, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(bot.workspace, membersFileName), data, 0644)
+}
+
+// setUser sets the user of the update being handled, remembering it as a
+// member of the chat.
+func (bot *ListBot) setUser(user gotto.User) {
+	bot.user = user
+	if user.Id == 0 || bot.members[user.Id] == user {
+		return
+	}
+	bot.members[user.Id] = user
+	if err := bot.saveMembers(); err != nil {
+		log.Printf("[ERROR ListBot cannot save members] Workspace {%s} Error {%s}", bot.workspace, err)
+	}
+}
+
+// resolveMember finds the member of the chat a user refers to with "me",
+// "@username" or a first name. Unknown "@username"s are kept by name.
+func (lb *ListBot) resolveMember(who string) (Assignee, bool) {
+	who = strings.TrimSpace(who)
+	if strings.EqualFold(who, "me") {
+		return Assignee{Id: lb.user.Id, Name: lb.user.Mention()}, true
+	}
+	if strings.HasPrefix(who, "@") {
+		for _, m := range lb.members {
+			if strings.EqualFold(m.UserName, who[1:]) {
+				return Assignee{Id: m.Id, Name: m.Mention()}, true
+			}
+		}
+		return Assignee{Name: who}, len(who) > 1 && !strings.ContainsAny(who, " \t")
+	}
+	for _, m := range lb.members {
+		if strings.EqualFold(m.FirstName, who) || strings.EqualFold(m.String(), who) {
+			return Assignee{Id: m.Id, Name: m.Mention()}, true
+		}
+	}
+	return Assignee{}, false
+}
+
+// is tells whether the item is assigned to the given user.
+func (a *Assignee) is(user gotto.User) bool {
+	if a == nil {
+		return false
+	}
+	if a.Id != 0 {
+		return a.Id == user.Id
+	}
+	return user.UserName != "" && strings.EqualFold(a.Name, "@"+user.UserName)
+}
+
+func (lb *ListBot) assignItem(list *List, pos string, who string) string {
+	idx, ok := parsePosition(list, pos)
+	if !ok {
+		return lb.tr("Invalid index %s", pos)
+	}
+	assignee, ok := lb.resolveMember(who)
+	if !ok {
+		return lb.tr("Unknown user %s. Use their @username", who)
+	}
+	list.items[idx].Assignee = &assignee
+	if msg := lb.saveList(list); msg != "" {
+		return msg
+	}
+	if assignee.Id != lb.user.Id {
+		notice := lb.tr("%s, %s assigned you '%s' in list '%s'", assignee.Name, lb.user.Mention(), list.items[idx].Text, list.name)
+		if _, err := lb.conversation.Send(&gotto.Reply{Text: notice}); err != nil {
+			log.Printf("[ERROR ListBot Cannot notify assignee] Workspace {%s} ListName {%s} Error {%s} ", lb.workspace, list.name, err)
+		}
+	}
+	return ""
+}
+
+func (lb *ListBot) unassignItem(list *List, pos string) string {
+	idx, ok := parsePosition(list, pos)
+	if !ok {
+		return lb.tr("Invalid index %s", pos)
+	}
+	if list.items[idx].Assignee == nil {
+		return lb.tr("Item %d is not assigned", idx)
+	}
+	list.items[idx].Assignee = nil
+	return lb.saveList(list)
+}
+
+// listMine prints the unchecked items assigned to the user across lists.
+func (lb *ListBot) listMine(s string) string {
+	var b strings.Builder
+	for _, l := range lb.sortedLists() {
+		for idx, item := range l.items {
+			if !item.Done && item.Assignee.is(lb.user) {
+				fmt.Fprintf(&b, "\n%s [%d] %s", l.name, idx, item)
+			}
+		}
+	}
+	if b.Len() == 0 {
+		return lb.tr("Nothing assigned to %s", lb.user.Mention())
+	}
+	return lb.tr("Assigned to %s:%s", lb.user.Mention(), b.String())
+}
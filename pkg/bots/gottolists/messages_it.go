@@ -0,0 +1,278 @@
+package gottolists
+
+import "github.com/gvisco/vi.sco/pkg/gotto"
+
+// The commands, their arguments like "owner" or "off" and the due dates like
+// "@tomorrow" are not translated, so that they work in every language.
+
+const helpStringIt string = `Comandi disponibili:
+/list all -- Mostra i nomi di tutte le liste
+/list view <nome> [pagina] -- Mostra il contenuto di una lista, tocca un elemento per spuntarlo
+/list new <nome> -- Crea una nuova lista con il nome dato
+/list new <nome> from <modello> -- Crea una nuova lista con gli elementi di un modello
+/list del <nome> -- Elimina una lista, spostandola nel cestino
+/list edit <nome> -- Modifica il contenuto di una lista
+/list add <nome> <elemento> -- Aggiunge un elemento in fondo a una lista
+/list rm <nome> <posizione> -- Rimuove da una lista un elemento, o un intervallo di elementi come 3-7
+/list check <nome> <posizione> -- Segna come fatto un elemento di una lista
+/list uncheck <nome> <posizione> -- Segna come non fatto un elemento di una lista
+/list rename <vecchio> <nuovo> -- Cambia il nome di una lista
+/list import <nome> -- Aggiunge a una lista gli elementi di un messaggio o di un file .txt, .md o .csv
+/list export <nome> [txt|md|csv|json] -- Invia una lista come file, "all" invia uno zip di tutte le liste
+/list undo <nome> -- Annulla l'ultima modifica a una lista
+/list history <nome> -- Mostra le ultime modifiche a una lista
+/list share <nome> -- Ottieni un codice per usare una lista in altre chat
+/list join <codice> -- Usa in questa chat una lista condivisa da un'altra chat
+/list unshare <nome> -- Smetti di condividere una lista con le altre chat
+/list template save <nome> -- Salva una lista come modello, con gli elementi non spuntati
+/list template del <nome> -- Elimina un modello
+/list templates -- Mostra i nomi di tutti i modelli
+/list repeat <nome> <programma> [from <modello>] -- Toglie la spunta agli elementi di una lista, o li sostituisce con un modello, secondo un programma come "mon 08:00", "daily 20:00" o "every 12h". "off" lo ferma
+/list repeats -- Mostra le liste che si ripetono
+/list find <testo> -- Cerca negli elementi di tutte le liste, tollerando gli errori di battitura
+/list due -- Mostra gli elementi con una scadenza di tutte le liste
+/list mine -- Mostra gli elementi assegnati a te in tutte le liste
+/list trash -- Mostra le liste eliminate, che vengono cancellate dopo un po'
+/list restore <nome> -- Recupera una lista eliminata dal cestino
+/list archive <nome> -- Nasconde da /list all una lista, ad esempio completata, senza eliminarla
+/list unarchive <nome> -- Mostra di nuovo in /list all una lista archiviata
+/list archived -- Mostra le liste archiviate
+/list role <nome> <utente> <owner|editor|viewer|none> -- Cambia il ruolo di un membro, o di "everyone" per tutti gli altri, su una lista. Gli editor cambiano gli elementi, i proprietari possono fare tutto
+/list roles <nome> -- Mostra i ruoli su una lista
+/list lock <nome> -- Rende una lista di sola lettura, anche per /list repeat. I proprietari possono comunque eliminarla
+/list unlock <nome> -- Rende di nuovo modificabile una lista bloccata
+/list pin <nome> -- Fissa un messaggio con la lista, tenuto aggiornato
+/list unpin <nome> -- Smetti di aggiornare il messaggio fissato di una lista
+I nomi possono contenere spazi: scrivili "tra virgolette" se seguiti da altri argomenti.
+Gli elementi possono avere una scadenza, ad esempio "compra il latte @tomorrow 18:00", "@friday", "@2021-06-30" o "@18:00": la chat riceve un promemoria alla scadenza.
+Gli elementi possono avere una quantità e un prezzo, ad esempio "2x latte €1.20" o "500g farina": aggiungere di nuovo lo stesso prodotto li somma, e /list view mostra i totali.
+/l e /ls sono le forme brevi di /list e /list all, e le liste si possono cambiare con frasi come "aggiungi uova a spesa" o "mostra spesa".
+/list help -- Mostra questo messaggio di aiuto
+/lang -- Mostra o cambia la tua lingua
+`
+
+const editHelpStringIt string = `Comandi disponibili per la modifica:
+/append <elemento> -- Aggiunge un nuovo elemento in fondo alla lista, o uno per riga. Termina un elemento con "@<giorno> [hh:mm]" per impostare una scadenza
+/rm <posizione> -- Rimuove un elemento, o un intervallo di elementi come 3-7
+/add <posizione> <elemento> -- Aggiunge un elemento nella posizione data
+/mv <da> <a> -- Sposta un elemento, o un intervallo di elementi come 2-4, in un'altra posizione
+/edit <posizione> <elemento> -- Sostituisce l'elemento nella posizione data
+/check <posizione> -- Segna un elemento come fatto
+/uncheck <posizione> -- Segna un elemento come non fatto
+/clear-done -- Rimuove tutti gli elementi segnati come fatti
+/sort [asc|desc|done|due] -- Ordina gli elementi di ogni sezione per testo, mettendo prima quelli non spuntati o per scadenza
+/dedup -- Rimuove gli elementi ripetuti
+/reverse -- Inverte l'ordine degli elementi
+/shuffle -- Mescola gli elementi
+/filter [testo] -- Mostra solo gli elementi che contengono un testo, o tutti se non è dato un testo
+/page <numero> -- Mostra una pagina di una lista lunga. Dopo ogni comando sono mostrati solo gli elementi cambiati
+/section <nome> -- Aggiunge l'intestazione di una sezione in fondo alla lista
+/indent <posizione> -- Rende un elemento, o un intervallo di elementi, figlio del precedente
+/outdent <posizione> -- Sposta un elemento, o un intervallo di elementi, di un livello verso l'alto
+/assign <posizione> <utente> -- Assegna un elemento a un membro della chat: @username, nome o "me"
+/unassign <posizione> -- Rimuove l'assegnatario di un elemento
+/undo -- Annulla l'ultima modifica
+/redo -- Ripete l'ultima modifica annullata
+/end -- Termina la modifica della lista
+/help -- Mostra questo messaggio di aiuto
+`
+
+func init() {
+	gotto.AddMessages("it", map[string]string{
+		helpString:     helpStringIt,
+		editHelpString: editHelpStringIt,
+
+		// roles
+		"owner":  "proprietario",
+		"editor": "editor",
+		"viewer": "lettore",
+		"none":   "nessuno",
+
+		// failed actions, see ListBot.abort
+		"Cannot %s '%s'. An error occurred": "Impossibile %s '%s'. Si è verificato un errore",
+		"archive list":                      "archiviare la lista",
+		"change the roles of list":          "cambiare i ruoli della lista",
+		"delete list":                       "eliminare la lista",
+		"delete template":                   "eliminare il modello",
+		"lock list":                         "bloccare la lista",
+		"read deleted list":                 "leggere la lista eliminata",
+		"redo the last change to list":      "ripetere l'ultima modifica alla lista",
+		"remove list":                       "rimuovere la lista",
+		"rename list":                       "rinominare la lista",
+		"restore list":                      "ripristinare la lista",
+		"save list":                         "salvare la lista",
+		"save template":                     "salvare il modello",
+		"save the schedule of list":         "salvare la programmazione della lista",
+		"schedule list":                     "programmare la lista",
+		"stop sharing list":                 "smettere di condividere la lista",
+		"undo the last change to list":      "annullare l'ultima modifica alla lista",
+
+		// commands, see commands.go
+		"Usage:":                         "Uso:",
+		"Unknown command. Did you mean:": "Comando sconosciuto. Forse intendevi:",
+
+		// lists
+		"Invalid list name: %s":                                                "Nome di lista non valido: %s",
+		"Invalid list: %s":                                                     "Lista non valida: %s",
+		"A list with name '%s' already exists":                                 "Esiste già una lista con nome '%s'",
+		"Your lists:":                                                          "Le tue liste:",
+		"(%d archived, see /list archived)":                                    "(%d archiviate, vedi /list archived)",
+		"Cannot export '%s'. An error occurred":                                "Impossibile esportare '%s'. Si è verificato un errore",
+		"List '%s' renamed to '%s'":                                            "Lista '%s' rinominata in '%s'",
+		"New list '%s' created with %d items":                                  "Nuova lista '%s' creata con %d elementi",
+		"Are you sure you want to delete list '%s'?":                           "Vuoi davvero eliminare la lista '%s'?",
+		"Please reply 'yes' or 'no'":                                           "Per favore rispondi 'sì' o 'no'",
+		"List '%s' removed from this chat":                                     "Lista '%s' rimossa da questa chat",
+		"List '%s' was deleted":                                                "La lista '%s' è stata eliminata",
+		"I'm listening. Add new items to list '%s'.\nWrite `/end` to complete": "Ti ascolto. Aggiungi nuovi elementi alla lista '%s'.\nScrivi `/end` per terminare",
+		"Send me the items to add to list '%s', as a message or a .txt, .md or .csv file.\nWrite `/end` to cancel": "Inviami gli elementi da aggiungere alla lista '%s', come messaggio o come file .txt, .md o .csv.\nScrivi `/end` per annullare",
+		"Import cancelled":                 "Importazione annullata",
+		"Cannot import '%s': %s":           "Impossibile importare '%s': %s",
+		"Imported %d items into list '%s'": "Importati %d elementi nella lista '%s'",
+
+		// views
+		"Total: %s":                      "Totale: %s",
+		"%s items":                       "%s elementi",
+		"1 item":                         "1 elemento",
+		"« Prev":                         "« Prec",
+		"Next »":                         "Succ »",
+		"--- %s (page %d/%d) ---":        "--- %s (pagina %d/%d) ---",
+		"--- %s (items %d-%d of %d) ---": "--- %s (elementi %d-%d di %d) ---",
+		"--- %s (filter: %s) ---":        "--- %s (filtro: %s) ---",
+		"…and %d more, write a longer filter to see them":                           "…e altri %d, scrivi un filtro più lungo per vederli",
+		"%d of %d items shown. Write `/filter` to show all":                         "%d elementi mostrati su %d. Scrivi `/filter` per mostrarli tutti",
+		"This view was out of date, nothing was changed. Here is the current list:": "Questa vista non era aggiornata, non è stato cambiato nulla. Ecco la lista attuale:",
+
+		// edit mode
+		"Editing list '%s'.\nWrite `/help` to see the available commands": "Modifica della lista '%s'.\nScrivi `/help` per vedere i comandi disponibili",
+		"Edit of list '%s' complete":                                      "Modifica della lista '%s' completata",
+		"Invalid input. Type `/help` if needed":                           "Comando non valido. Scrivi `/help` se serve",
+		"Pages are numbered from 1":                                       "Le pagine sono numerate da 1",
+		"Invalid index %s":                                                "Posizione non valida: %s",
+		"Invalid 'from' index %s":                                         "Posizione di partenza non valida: %s",
+		"Invalid 'to' index %s":                                           "Posizione di arrivo non valida: %s",
+		"Item %d is a section":                                            "L'elemento %d è una sezione",
+		"Nothing to undo":                                                 "Niente da annullare",
+		"Undone '%s' by %s":                                               "Annullato '%s' di %s",
+		"Nothing to redo":                                                 "Niente da ripetere",
+		"Redone '%s' by %s":                                               "Ripetuto '%s' di %s",
+		"List '%s' changed since '%s' by %s, cannot undo it":              "La lista '%s' è cambiata dopo '%s' di %s, impossibile annullarlo",
+		"List '%s' changed since '%s' by %s was undone, cannot redo it":   "La lista '%s' è cambiata da quando '%s' di %s è stato annullato, impossibile ripeterlo",
+		"Invalid order %s. Use asc, desc, done or due":                    "Ordine %s non valido. Usa asc, desc, done o due",
+		"No duplicated items":                                             "Nessun elemento ripetuto",
+		"No checked items to clear":                                       "Nessun elemento spuntato da rimuovere",
+		"Invalid section name":                                            "Nome di sezione non valido",
+		"Item %d cannot be indented further":                              "L'elemento %d non può essere rientrato di più",
+		"Item %d is not indented":                                         "L'elemento %d non è rientrato",
+
+		// timeouts
+		"No new items for %s: list '%s' created with %d items":     "Nessun nuovo elemento per %s: lista '%s' creata con %d elementi",
+		"No edits for %s: edit of list '%s' complete":              "Nessuna modifica per %s: modifica della lista '%s' completata",
+		"No answer for %s: list '%s' was not deleted":              "Nessuna risposta per %s: la lista '%s' non è stata eliminata",
+		"Nothing received for %s: import into list '%s' cancelled": "Niente ricevuto per %s: importazione nella lista '%s' annullata",
+		"No input for %s: going back to waiting":                   "Nessun comando per %s: torno in attesa",
+
+		// history
+		"No changes recorded for list '%s'": "Nessuna modifica registrata per la lista '%s'",
+		"--- %s: history ---":               "--- %s: cronologia ---",
+		"(undone)":                          "(annullata)",
+
+		// sharing and pins
+		"Cannot share list '%s': %s": "Impossibile condividere la lista '%s': %s",
+		"List '%s' is shared. To use it in another chat write there:\n/list join %s": "La lista '%s' è condivisa. Per usarla in un'altra chat scrivi lì:\n/list join %s",
+		"List '%s' is shared, stop sharing it before renaming it":                    "La lista '%s' è condivisa, smetti di condividerla prima di rinominarla",
+		"List '%s' is not shared":                                   "La lista '%s' non è condivisa",
+		"Cannot stop sharing list '%s': %s":                         "Impossibile smettere di condividere la lista '%s': %s",
+		"List '%s' is not shared anymore":                           "La lista '%s' non è più condivisa",
+		notSharedNotice:                                             "La lista '%s' non è più condivisa con questa chat",
+		"Cannot join the list: %s":                                  "Impossibile unirsi alla lista: %s",
+		"List '%s' is now available in this chat":                   "La lista '%s' ora è disponibile in questa chat",
+		"Cannot pin list '%s'. Is the bot allowed to pin messages?": "Impossibile fissare la lista '%s'. Il bot può fissare i messaggi?",
+		"List '%s' is not pinned":                                   "La lista '%s' non è fissata",
+		"List '%s' is not pinned anymore":                           "La lista '%s' non è più fissata",
+		"list not shared anymore":                                   "la lista non è più condivisa",
+		"list '%s' belongs to another chat":                         "la lista '%s' appartiene a un'altra chat",
+		"only the user who shared list '%s' can stop sharing it":    "solo chi ha condiviso la lista '%s' può smettere di condividerla",
+		"invalid token %s":                                          "codice %s non valido",
+		"list '%s' is already available in this chat":               "la lista '%s' è già disponibile in questa chat",
+		"a list with name '%s' already exists, rename it first":     "esiste già una lista con nome '%s', rinominala prima",
+
+		// due dates and assignees
+		"No items with a due date":              "Nessun elemento con una scadenza",
+		"Due items:":                            "Elementi in scadenza:",
+		"(overdue)":                             "(scaduto)",
+		"%s '%s' is due (%s, list '%s')":        "%s '%s' è in scadenza (%s, lista '%s')",
+		"Snooze %s":                             "Rimanda di %s",
+		"This reminder is not valid anymore":    "Questo promemoria non è più valido",
+		"%s '%s' snoozed until %s":              "%s '%s' rimandato a %s",
+		"Unknown user %s. Use their @username":  "Utente %s sconosciuto. Usa il suo @username",
+		"%s, %s assigned you '%s' in list '%s'": "%s, %s ti ha assegnato '%s' nella lista '%s'",
+		"Item %d is not assigned":               "L'elemento %d non è assegnato",
+		"Nothing assigned to %s":                "Niente assegnato a %s",
+		"Assigned to %s:%s":                     "Assegnati a %s:%s",
+		"Nothing found for '%s'":                "Niente trovato per '%s'",
+		"Found for '%s':":                       "Trovati per '%s':",
+		"...and %d more":                        "...e altri %d",
+		"(similar)":                             "(simile)",
+		"Open '%s'":                             "Apri '%s'",
+
+		// templates and recurrences
+		"Invalid template name: %s": "Nome di modello non valido: %s",
+		"Template '%s' saved with %d items. Use it with:\n/list new <name> from %s": "Modello '%s' salvato con %d elementi. Usalo con:\n/list new <nome> from %s",
+		"Template '%s' deleted":                                                "Modello '%s' eliminato",
+		"Cannot read the templates. An error occurred":                         "Impossibile leggere i modelli. Si è verificato un errore",
+		"No templates. Save one with /list template save <name>":               "Nessun modello. Salvane uno con /list template save <nome>",
+		"Your templates:":                                                      "I tuoi modelli:",
+		"%s from template '%s'":                                                "%s dal modello '%s'",
+		"Cannot reset list '%s': template '%s' not found":                      "Impossibile ripristinare la lista '%s': modello '%s' non trovato",
+		"List '%s' was reset (%s)":                                             "La lista '%s' è stata ripristinata (%s)",
+		"List '%s' is locked and was not reset (%s)":                           "La lista '%s' è bloccata e non è stata ripristinata (%s)",
+		"List '%s' does not repeat":                                            "La lista '%s' non si ripete",
+		"List '%s' belongs to another chat, only that chat can make it repeat": "La lista '%s' appartiene a un'altra chat, solo quella chat può farla ripetere",
+		"List '%s' does not repeat anymore":                                    "La lista '%s' non si ripete più",
+		"Invalid schedule: %s. Use \"every <duration>\", \"daily <hh:mm>\" or \"<weekday> <hh:mm>\"": "Programma non valido: %s. Usa \"every <durata>\", \"daily <hh:mm>\" o \"<giorno> <hh:mm>\"",
+		"List '%s' will be reset %s, next on %s":                                                     "La lista '%s' sarà ripristinata %s, la prossima volta il %s",
+		"No repeating lists. Set one with /list repeat <name> <schedule>":                            "Nessuna lista che si ripete. Impostane una con /list repeat <nome> <programma>",
+		"Repeating lists:": "Liste che si ripetono:",
+
+		// trash and archive
+		"Cannot read the trash. An error occurred": "Impossibile leggere il cestino. Si è verificato un errore",
+		"The trash is empty":                       "Il cestino è vuoto",
+		"Deleted lists:":                           "Liste eliminate:",
+		"- %s (%d items), deleted on %s":           "- %s (%d elementi), eliminata il %s",
+		", purged on %s":                           ", cancellata il %s",
+		"Restore one with /list restore <name>":    "Recuperane una con /list restore <nome>",
+		"List '%s' moved to the trash until %s. Restore it with /list restore %s": "Lista '%s' spostata nel cestino fino al %s. Recuperala con /list restore %s",
+		"List '%s' moved to the trash. Restore it with /list restore %s":          "Lista '%s' spostata nel cestino. Recuperala con /list restore %s",
+		"A list with name '%s' already exists, rename it before restoring":        "Esiste già una lista con nome '%s', rinominala prima di recuperare",
+		"List '%s' is not in the trash":                                           "La lista '%s' non è nel cestino",
+		"List '%s' restored with %d items":                                        "Lista '%s' recuperata con %d elementi",
+		"List '%s' is already archived":                                           "La lista '%s' è già archiviata",
+		"List '%s' is not archived":                                               "La lista '%s' non è archiviata",
+		"List '%s' archived. It is hidden from /list all, see /list archived":     "Lista '%s' archiviata. È nascosta da /list all, vedi /list archived",
+		"List '%s' is back in /list all":                                          "La lista '%s' è di nuovo in /list all",
+		"No archived lists. Archive one with /list archive <name>":                "Nessuna lista archiviata. Archiviane una con /list archive <nome>",
+		"Archived lists:": "Liste archiviate:",
+
+		// roles and locks
+		"List '%s' is locked. Its owners can unlock it with /list unlock %s":  "La lista '%s' è bloccata. I suoi proprietari possono sbloccarla con /list unlock %s",
+		"Only the owners of list '%s' can do that":                            "Solo i proprietari della lista '%s' possono farlo",
+		"%s can only view list '%s'":                                          "%s può solo vedere la lista '%s'",
+		"Owners must be given one by one":                                     "I proprietari vanno indicati uno alla volta",
+		"Users without a role are viewers of list '%s'":                       "Gli utenti senza un ruolo sono lettori della lista '%s'",
+		"Users without a role are editors of list '%s'":                       "Gli utenti senza un ruolo sono editor della lista '%s'",
+		"Unknown user %s. They must write in this chat first":                 "Utente %s sconosciuto. Deve prima scrivere in questa chat",
+		"%s has the default role on list '%s'":                                "%s ha il ruolo predefinito sulla lista '%s'",
+		"%s is now %s of list '%s'":                                           "%s ora è %s della lista '%s'",
+		"List '%s' must have an owner":                                        "La lista '%s' deve avere un proprietario",
+		"List '%s' is open to everyone. Claim it with /list role %s me owner": "La lista '%s' è aperta a tutti. Prendila con /list role %s me owner",
+		"user %d":                        "utente %d",
+		"- %s else: %s":                  "- tutti gli altri (%s): %s",
+		"The list is locked":             "La lista è bloccata",
+		"Roles of list '%s':\n%s":        "Ruoli della lista '%s':\n%s",
+		"List '%s' is already locked":    "La lista '%s' è già bloccata",
+		"List '%s' is not locked":        "La lista '%s' non è bloccata",
+		"List '%s' is now read-only":     "La lista '%s' ora è di sola lettura",
+		"List '%s' can be changed again": "La lista '%s' può essere modificata di nuovo",
+	})
+}
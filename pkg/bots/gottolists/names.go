@@ -0,0 +1,60 @@
+package gottolists
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// nameArg matches a list name followed by other arguments: either a single
+// word or any text within (straight or curly) double quotes.
+const nameArg string = `("[^"]+"|“[^”]+”|[^ "“]+)`
+
+const maxNameLength int = 64
+
+// unquote trims the spaces and the quotes around a list name.
+func unquote(name string) string {
+	name = strings.TrimSpace(name)
+	for _, q := range [][2]string{{`"`, `"`}, {"“", "”"}} {
+		if len(name) > len(q[0]) && strings.HasPrefix(name, q[0]) && strings.HasSuffix(name, q[1]) {
+			return strings.TrimSpace(name[len(q[0]) : len(name)-len(q[1])])
+		}
+	}
+	return name
+}
+
+// slugify turns a display name into the identifier of a list, also used as
+// its file name: letters and digits are lowercased, any other run of
+// characters becomes a single dash. Names of different case or punctuation
+// map to the same list, and the result can never escape the workspace.
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteRune('-')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// validName checks a list name given by a user, returning its identifier.
+func validName(name string) (string, bool) {
+	if utf8.RuneCountInString(name) > maxNameLength || strings.ContainsAny(name, "\n\r\t") {
+		return "", false
+	}
+	id := slugify(name)
+	return id, id != ""
+}
+
+// findList looks a list up by the name given by a user.
+func (lb *ListBot) findList(name string) (*List, bool) {
+	l, ok := lb.lists[slugify(unquote(name))]
+	return l, ok
+}
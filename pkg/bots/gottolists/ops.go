@@ -0,0 +1,215 @@
+package gottolists
+
+import (
+	"log"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The operations below are shared by the edit mode and the one-shot /list
+// commands: they validate their arguments, change the list and save it. They
+// return a message for the user when something goes wrong, "" otherwise.
+
+// saveList fixes the indentation of the list, saves it and records the change
+// in its journal, on behalf of the current user and action, then updates the
+// pinned messages.
+func (lb *ListBot) saveList(list *List) string {
+	list.normalize()
+	before := list.saved
+	if err := list.saveToFile(); err != nil {
+		return lb.abort("save list", list.name, err)
+	}
+	if err := list.record(lb.action, lb.user.String(), before); err != nil {
+		log.Printf("[ERROR ListBot Cannot record change] Workspace {%s} ListName {%s} Error {%s} ", lb.workspace, list.name, err)
+	}
+	lb.listChanged(list)
+	return ""
+}
+
+func parsePosition(list *List, arg string) (int, bool) {
+	idx, err := strconv.Atoi(arg)
+	if err != nil || idx < 0 || idx >= len(list.items) {
+		return 0, false
+	}
+	return idx, true
+}
+
+// parseRange reads a position or a range of positions like "3-7", returning
+// the first and the last position of the range.
+func parseRange(list *List, arg string) (int, int, bool) {
+	bounds := strings.SplitN(arg, "-", 2)
+	first, ok := parsePosition(list, bounds[0])
+	if !ok {
+		return 0, 0, false
+	}
+	if len(bounds) == 1 {
+		return first, first, true
+	}
+	last, ok := parsePosition(list, bounds[1])
+	if !ok || last < first {
+		return 0, 0, false
+	}
+	return first, last, true
+}
+
+// appendItems adds the items of a message to the bottom of the list, one per
+// line or separated chunk (see splitItems). Items with a quantity or a price
+// are merged into the unchecked item of the same product, if any.
+func (lb *ListBot) appendItems(list *List, text string) string {
+	texts := splitItems(text, lb.config.ItemSeparators)
+	if len(texts) == 0 {
+		return ""
+	}
+	now := time.Now()
+	for _, t := range texts {
+		if item := parseItem(t, now); !list.merge(item) {
+			list.addItem(item)
+		}
+	}
+	return lb.saveList(list)
+}
+
+func (lb *ListBot) insertItem(list *List, pos string, text string) string {
+	idx, ok := parsePosition(list, pos)
+	if !ok {
+		return lb.tr("Invalid index %s", pos)
+	}
+	list.insert(parseItem(text, time.Now()), idx)
+	return lb.saveList(list)
+}
+
+func (lb *ListBot) removeItem(list *List, pos string) string {
+	first, last, ok := parseRange(list, pos)
+	if !ok {
+		return lb.tr("Invalid index %s", pos)
+	}
+	list.removeRange(first, last)
+	return lb.saveList(list)
+}
+
+// moveItem moves an item, or a range of items, so that the (first) item ends
+// up at the given position.
+func (lb *ListBot) moveItem(list *List, from string, to string) string {
+	first, last, ok := parseRange(list, from)
+	if !ok {
+		return lb.tr("Invalid 'from' index %s", from)
+	}
+	dst, err := strconv.Atoi(to)
+	if err != nil || dst < 0 || dst > len(list.items)-(last-first+1) {
+		return lb.tr("Invalid 'to' index %s", to)
+	}
+	list.moveRange(first, last, dst)
+	return lb.saveList(list)
+}
+
+func (lb *ListBot) editItem(list *List, pos string, text string) string {
+	idx, ok := parsePosition(list, pos)
+	if !ok {
+		return lb.tr("Invalid index %s", pos)
+	}
+	// the due date is kept unless a new one is given
+	item := parseItem(text, time.Now())
+	list.items[idx].Text = item.Text
+	if item.Due != nil {
+		list.items[idx].Due = item.Due
+	}
+	return lb.saveList(list)
+}
+
+func (lb *ListBot) setDone(list *List, pos string, done bool) string {
+	idx, ok := parsePosition(list, pos)
+	if !ok {
+		return lb.tr("Invalid index %s", pos)
+	}
+	if list.items[idx].Section {
+		return lb.tr("Item %d is a section", idx)
+	}
+	list.items[idx].Done = done
+	return lb.saveList(list)
+}
+
+// undo and redo also report the change they reverted or restored.
+
+func (lb *ListBot) undo(list *List) string {
+	op, err := list.undo()
+	if err == errListChanged {
+		return lb.tr("List '%s' changed since '%s' by %s, cannot undo it", list.name, op.Action, op.User)
+	}
+	if err != nil {
+		return lb.abort("undo the last change to list", list.name, err)
+	}
+	if op == nil {
+		return lb.tr("Nothing to undo")
+	}
+	lb.listChanged(list)
+	return lb.tr("Undone '%s' by %s", op.Action, op.User)
+}
+
+func (lb *ListBot) redo(list *List) string {
+	op, err := list.redo()
+	if err == errListChanged {
+		return lb.tr("List '%s' changed since '%s' by %s was undone, cannot redo it", list.name, op.Action, op.User)
+	}
+	if err != nil {
+		return lb.abort("redo the last change to list", list.name, err)
+	}
+	if op == nil {
+		return lb.tr("Nothing to redo")
+	}
+	lb.listChanged(list)
+	return lb.tr("Redone '%s' by %s", op.Action, op.User)
+}
+
+// sortItems sorts a list alphabetically ("asc", the default, or "desc"),
+// with the unchecked items first ("done") or by due date ("due"). Items
+// comparing equal keep their order. Like reverse and shuffle, it reorders the
+// top level items within each section, see List.reorder.
+func (lb *ListBot) sortItems(list *List, order string) string {
+	var less func(a Item, b Item) bool
+	switch order {
+	case "", "asc":
+		less = func(a Item, b Item) bool { return strings.ToLower(a.Text) < strings.ToLower(b.Text) }
+	case "desc":
+		less = func(a Item, b Item) bool { return strings.ToLower(a.Text) > strings.ToLower(b.Text) }
+	case "done":
+		less = func(a Item, b Item) bool { return !a.Done && b.Done }
+	case "due":
+		less = func(a Item, b Item) bool { return a.Due != nil && (b.Due == nil || a.Due.Before(*b.Due)) }
+	default:
+		return lb.tr("Invalid order %s. Use asc, desc, done or due", order)
+	}
+	list.reorder(func(units [][]Item) {
+		sort.SliceStable(units, func(i, j int) bool { return less(units[i][0], units[j][0]) })
+	})
+	return lb.saveList(list)
+}
+
+func (lb *ListBot) dedupItems(list *List) string {
+	if list.dedup() == 0 {
+		return lb.tr("No duplicated items")
+	}
+	return lb.saveList(list)
+}
+
+func (lb *ListBot) reverseItems(list *List) string {
+	list.reverse()
+	return lb.saveList(list)
+}
+
+func (lb *ListBot) shuffleItems(list *List) string {
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	list.reorder(func(units [][]Item) {
+		random.Shuffle(len(units), func(i, j int) { units[i], units[j] = units[j], units[i] })
+	})
+	return lb.saveList(list)
+}
+
+func (lb *ListBot) clearDone(list *List) string {
+	if list.clearDone() == 0 {
+		return lb.tr("No checked items to clear")
+	}
+	return lb.saveList(list)
+}
@@ -0,0 +1,132 @@
+package gottolists
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gvisco/vi.sco/pkg/gotto"
+)
+
+// Lists longer than Config.PageSize are shown one page at a time: views get
+// buttons to move between the pages, while the edit mode shows the items
+// changed by each command. A page size of zero shows the whole lists, with
+// their buttons still paged, see viewReply.
+
+// pageCallback moves the view of a list to another page
+const pageCallback string = "lists:page:"
+
+// changeContext is the number of unchanged items shown around the changed
+// ones in edit mode.
+const changeContext int = 2
+
+// page returns the bounds of a page, from first to last excluded, clamping
+// the page number, and the number of pages.
+func (list *List) page(page int, size int) (int, int, int, int) {
+	if size <= 0 || len(list.items) <= size {
+		return 0, 0, len(list.items), 1
+	}
+	pages := (len(list.items) + size - 1) / size
+	if page >= pages {
+		page = pages - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+	first := page * size
+	last := first + size
+	if last > len(list.items) {
+		last = len(list.items)
+	}
+	return page, first, last, pages
+}
+
+// renderItems renders the items from first to last excluded, with their
+// positions in the whole list.
+func (list *List) renderItems(b *strings.Builder, first int, last int) {
+	for idx := first; idx < last; idx++ {
+		fmt.Fprintf(b, "\n[%d] %s%s", idx, strings.Repeat(indentation, list.depth(idx)), list.items[idx])
+	}
+}
+
+// renderPage renders a page of the list, or the whole list if it fits.
+func (list *List) renderPage(loc gotto.Locale, page int, size int) string {
+	page, first, last, pages := list.page(page, size)
+	if pages == 1 {
+		return list.render()
+	}
+	var b strings.Builder
+	b.WriteString(loc.Sprintf("--- %s (page %d/%d) ---", list.name, page+1, pages))
+	list.renderItems(&b, first, last)
+	return b.String()
+}
+
+// renderChange renders the items changed since before, with a few items of
+// context and a confirmation of the action, or the whole list if it fits in
+// a page. Lists which did not change are rendered at the given page.
+func (lb *ListBot) renderChange(list *List, before []Item, page int) string {
+	size := lb.config.PageSize
+	if size <= 0 || len(list.items) <= size {
+		return list.render()
+	}
+	first := 0
+	for first < len(before) && first < len(list.items) && reflect.DeepEqual(before[first], list.items[first]) {
+		first++
+	}
+	if first == len(before) && first == len(list.items) {
+		return list.renderPage(lb.locale(), page, size)
+	}
+	old, last := len(before), len(list.items)
+	for old > first && last > first && reflect.DeepEqual(before[old-1], list.items[last-1]) {
+		old--
+		last--
+	}
+	// removed items leave an empty range, show what is around it
+	if last == first {
+		last = first + 1
+	}
+	from, to := first-changeContext, last+changeContext
+	if from < 0 {
+		from = 0
+	}
+	if to > from+size {
+		to = from + size
+	}
+	if to > len(list.items) {
+		to = len(list.items)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "✔ %s\n", lb.action)
+	b.WriteString(lb.tr("--- %s (items %d-%d of %d) ---", list.name, from, to-1, len(list.items)))
+	if from > 0 {
+		b.WriteString("\n…")
+	}
+	list.renderItems(&b, from, to)
+	if to < len(list.items) {
+		b.WriteString("\n…")
+	}
+	return b.String()
+}
+
+// turnPage replaces a view with another page of the list.
+func (bot *ListBot) turnPage(args []string) *gotto.Reply {
+	list, ok := bot.lists[args[1]]
+	if !ok {
+		return &gotto.Reply{Text: bot.tr("Invalid list: %s", args[1])}
+	}
+	page, err := strconv.Atoi(args[0])
+	if err != nil {
+		return nil
+	}
+	return list.viewReply(bot.locale(), page, bot.config.PageSize)
+}
+
+// parsePage reads a page number written by the user, counting from one.
+func parsePage(arg string) (int, bool) {
+	page, err := strconv.Atoi(arg)
+	if err != nil || page < 1 {
+		return 0, false
+	}
+	return page - 1, true
+}
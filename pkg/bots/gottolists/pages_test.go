@@ -0,0 +1,45 @@
+package gottolists
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gvisco/vi.sco/pkg/gotto"
+)
+
+func TestViewReplyPages(t *testing.T) {
+	list := &List{id: "shopping", name: "shopping"}
+	for i := 0; i < 120; i++ {
+		list.items = append(list.items, Item{Text: fmt.Sprintf("item %d", i)})
+	}
+	tests := []struct {
+		size, page int
+		// items are the number of item rows, nav the buttons to other pages
+		items, nav int
+		whole      bool
+	}{
+		{0, 0, maxItemButtons, 1, true},
+		{0, 1, maxItemButtons, 2, true},
+		{0, 2, 120 - 2*maxItemButtons, 1, true},
+		{0, 9, 120 - 2*maxItemButtons, 1, true},
+		{20, 0, 20, 1, false},
+		{20, 3, 20, 2, false},
+		{200, 0, maxItemButtons, 0, true},
+	}
+	for _, tt := range tests {
+		reply := list.viewReply(gotto.Locale("en"), tt.page, tt.size)
+		rows := reply.Keyboard
+		nav := 0
+		if len(rows) > 0 && strings.HasPrefix(rows[len(rows)-1][0].Data, pageCallback) {
+			nav = len(rows[len(rows)-1])
+			rows = rows[:len(rows)-1]
+		}
+		if len(rows) != tt.items || nav != tt.nav {
+			t.Errorf("size %d page %d: %d item buttons and %d page buttons, want %d and %d", tt.size, tt.page, len(rows), nav, tt.items, tt.nav)
+		}
+		if whole := strings.Contains(reply.Text, "item 0\n") && strings.Contains(reply.Text, "item 119\n"); whole != tt.whole {
+			t.Errorf("size %d page %d: whole list shown %v, want %v", tt.size, tt.page, whole, tt.whole)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package gottolists
+
+import (
+	"log"
+
+	"github.com/gvisco/vi.sco/pkg/gotto"
+)
+
+// pinWatchers maps the files of the pinned lists to the bots which pinned
+// them, so that a change made in a chat updates the pinned messages of all the
+// chats sharing the list. It is guarded by sharedMu.
+var pinWatchers map[string]map[*ListBot]bool = make(map[string]map[*ListBot]bool)
+
+// pinKey identifies the pinned message of a list in a conversation.
+func pinKey(id string) string {
+	return "lists:" + id
+}
+
+func (bot *ListBot) watch(path string) {
+	if pinWatchers[path] == nil {
+		pinWatchers[path] = make(map[*ListBot]bool)
+	}
+	pinWatchers[path][bot] = true
+}
+
+func (bot *ListBot) unwatch(path string) {
+	delete(pinWatchers[path], bot)
+	if len(pinWatchers[path]) == 0 {
+		delete(pinWatchers, path)
+	}
+}
+
+// listByPath returns the list of the chat stored in the given file.
+func (bot *ListBot) listByPath(path string) (*List, bool) {
+	for _, l := range bot.lists {
+		if l.filePath == path {
+			return l, true
+		}
+	}
+	return nil, false
+}
+
+// watchPins brings up to date the lists pinned in the chat before the bot
+// was created.
+func (bot *ListBot) watchPins() {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	for id, list := range bot.lists {
+		if !bot.conversation.Pinned(pinKey(id)) {
+			continue
+		}
+		bot.watch(list.filePath)
+		if err := bot.conversation.UpdatePinned(pinKey(id), list.viewReply(bot.locale(), 0, bot.config.PageSize)); err != nil {
+			log.Printf("[ERROR ListBot Cannot update pinned list] Workspace {%s} ListName {%s} Error {%s} ", bot.workspace, list.name, err)
+		}
+	}
+}
+
+// pin posts the list and pins it, or refreshes the message if it was already
+// pinned.
+func (lb *ListBot) pin(s string) string {
+	lname := rePinList.FindStringSubmatch(s)[1]
+	l, ok := lb.findList(lname)
+	if !ok {
+		return lb.tr("Invalid list name: %s", unquote(lname))
+	}
+	if err := lb.conversation.Pin(pinKey(l.id), l.viewReply(lb.locale(), 0, lb.config.PageSize)); err != nil {
+		log.Printf("[ERROR ListBot Cannot pin list] Workspace {%s} ListName {%s} Error {%s} ", lb.workspace, l.name, err)
+		return lb.tr("Cannot pin list '%s'. Is the bot allowed to pin messages?", l.name)
+	}
+	lb.watch(l.filePath)
+	return ""
+}
+
+func (lb *ListBot) unpin(s string) string {
+	lname := reUnpinList.FindStringSubmatch(s)[1]
+	l, ok := lb.findList(lname)
+	if !ok {
+		return lb.tr("Invalid list name: %s", unquote(lname))
+	}
+	if !lb.conversation.Pinned(pinKey(l.id)) {
+		return lb.tr("List '%s' is not pinned", l.name)
+	}
+	lb.unpinList(l, nil)
+	return lb.tr("List '%s' is not pinned anymore", l.name)
+}
+
+// unpinList unpins the message of a list, replacing it with reply if not nil.
+func (bot *ListBot) unpinList(list *List, reply *gotto.Reply) {
+	bot.unwatch(list.filePath)
+	if err := bot.conversation.Unpin(pinKey(list.id), reply); err != nil {
+		log.Printf("[ERROR ListBot Cannot unpin list] Workspace {%s} ListName {%s} Error {%s} ", bot.workspace, list.name, err)
+	}
+}
+
+// listChanged updates the pinned messages of a list in all the chats.
+func (lb *ListBot) listChanged(list *List) {
+	path := list.filePath
+	for bot := range pinWatchers[path] {
+		bot.notify(lb, func(bot *ListBot) {
+			l, ok := bot.listByPath(path)
+			if !ok {
+				return
+			}
+			if err := l.refresh(); err != nil {
+				log.Printf("[ERROR ListBot Cannot reload pinned list] Workspace {%s} File {%s} Error {%s}", bot.workspace, l.filePath, err)
+				return
+			}
+			if err := bot.conversation.UpdatePinned(pinKey(l.id), l.viewReply(bot.locale(), 0, bot.config.PageSize)); err != nil {
+				log.Printf("[ERROR ListBot Cannot update pinned list] Workspace {%s} ListName {%s} Error {%s} ", bot.workspace, l.name, err)
+			}
+		})
+	}
+}
+
+// listRemoved unpins a list which is not available anymore from the chats
+// where it was pinned, replacing the messages with reason, a message taking
+// the name of the list, in the language of each chat. With linkedOnly only
+// the chats which joined the list are affected.
+func (lb *ListBot) listRemoved(list *List, linkedOnly bool, reason string) {
+	path := list.filePath
+	for bot := range pinWatchers[path] {
+		bot.notify(lb, func(bot *ListBot) {
+			l, ok := bot.listByPath(path)
+			if !ok || (linkedOnly && !l.linked) {
+				return
+			}
+			bot.unpinList(l, &gotto.Reply{Text: bot.tr(reason, l.name)})
+		})
+	}
+}
+
+// notify runs f on a bot watching a list changed by the current one. The
+// other bots belong to other conversations: f runs in their goroutine, like
+// their own timers, holding sharedMu like their handlers.
+func (bot *ListBot) notify(current *ListBot, f func(*ListBot)) {
+	if bot == current {
+		f(bot)
+		return
+	}
+	bot.conversation.AfterFunc(0, func() string {
+		sharedMu.Lock()
+		defer sharedMu.Unlock()
+		f(bot)
+		return ""
+	})
+}
@@ -0,0 +1,220 @@
+package gottolists
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gvisco/vi.sco/pkg/gotto"
+)
+
+// recurrencesFileName stores the recurrences of the lists of the chat
+const recurrencesFileName string = "listbot.recurrences"
+
+// recurrence periodically resets a list: its items are unchecked or, if a
+// template is given, replaced with the ones of the template.
+type recurrence struct {
+	Schedule string
+	Template string `json:",omitempty"`
+	// Last is the time of the last reset, to catch up after a restart
+	Last time.Time
+	// id is the list the recurrence belongs to
+	id    string
+	timer *gotto.Timer
+}
+
+func (r *recurrence) String() string {
+	if r.Template != "" {
+		return fmt.Sprintf("%s from template '%s'", r.Schedule, r.Template)
+	}
+	return r.Schedule
+}
+
+// describe prints a recurrence for the user.
+func (lb *ListBot) describe(r *recurrence) string {
+	if r.Template != "" {
+		return lb.tr("%s from template '%s'", r.Schedule, r.Template)
+	}
+	return r.Schedule
+}
+
+func (bot *ListBot) loadRecurrences() {
+	bot.recurrences = make(map[string]*recurrence)
+	data, err := ioutil.ReadFile(filepath.Join(bot.workspace, recurrencesFileName))
+	if os.IsNotExist(err) {
+		return
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &bot.recurrences)
+	}
+	if err != nil {
+		log.Printf("[ERROR ListBot cannot read recurrences] Workspace {%s} Error {%s}", bot.workspace, err)
+		return
+	}
+	for id, r := range bot.recurrences {
+		if l, ok := bot.lists[id]; !ok || l.linked {
+			log.Printf("[ListBot removing recurrence] Workspace {%s} List {%s} Reason {list not found or owned by another chat}", bot.workspace, id)
+			delete(bot.recurrences, id)
+			continue
+		}
+		r.id = id
+		if err := bot.armRecurrence(r); err != nil {
+			log.Printf("[ERROR ListBot cannot schedule recurrence] Workspace {%s} List {%s} Error {%s}", bot.workspace, id, err)
+		}
+	}
+}
+
+func (bot *ListBot) saveRecurrences() error {
+	data, err := json.MarshalIndent(bot.recurrences, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(bot.workspace, recurrencesFileName), data, 0644)
+}
+
+func (bot *ListBot) armRecurrence(r *recurrence) error {
+	schedule, err := gotto.ParseSchedule(r.Schedule)
+	if err != nil {
+		return err
+	}
+	r.timer = bot.conversation.Repeat(schedule, r.Last, func() string { return bot.recur(r) })
+	return nil
+}
+
+// stopRecurrence removes the recurrence of a list, if any.
+func (bot *ListBot) stopRecurrence(id string) {
+	r, ok := bot.recurrences[id]
+	if !ok {
+		return
+	}
+	r.timer.Stop()
+	delete(bot.recurrences, id)
+	if err := bot.saveRecurrences(); err != nil {
+		log.Printf("[ERROR ListBot cannot save recurrences] Workspace {%s} Error {%s}", bot.workspace, err)
+	}
+}
+
+// moveRecurrence follows a list being renamed.
+func (bot *ListBot) moveRecurrence(from string, to string) {
+	r, ok := bot.recurrences[from]
+	if !ok {
+		return
+	}
+	delete(bot.recurrences, from)
+	r.id = to
+	bot.recurrences[to] = r
+	if err := bot.saveRecurrences(); err != nil {
+		log.Printf("[ERROR ListBot cannot save recurrences] Workspace {%s} Error {%s}", bot.workspace, err)
+	}
+}
+
+// recur resets a list when its recurrence is due. Locked lists are skipped.
+func (bot *ListBot) recur(r *recurrence) string {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	notice := bot.refreshShared()
+	list, ok := bot.lists[r.id]
+	if !ok {
+		bot.stopRecurrence(r.id)
+		return notice
+	}
+	if list.locked {
+		r.Last = time.Now()
+		if err := bot.saveRecurrences(); err != nil {
+			log.Printf("[ERROR ListBot cannot save recurrences] Workspace {%s} Error {%s}", bot.workspace, err)
+		}
+		return notice + bot.tr("List '%s' is locked and was not reset (%s)", list.name, bot.describe(r))
+	}
+	defer func(user gotto.User) { bot.user = user }(bot.user)
+	bot.user = gotto.User{FirstName: "schedule"}
+	bot.action = fmt.Sprintf("reset %s", r)
+	previous := list.items
+	if r.Template != "" {
+		template, err := bot.loadTemplate(r.Template)
+		if err != nil || template == nil {
+			log.Printf("[ERROR ListBot Cannot read template] Workspace {%s} Template {%s} Error {%v} ", bot.workspace, r.Template, err)
+			return notice + bot.tr("Cannot reset list '%s': template '%s' not found", list.name, r.Template)
+		}
+		list.items = template.templateItems()
+	} else {
+		list.items = append([]Item{}, list.items...)
+		for idx := range list.items {
+			list.items[idx].Done = false
+		}
+	}
+	if msg := bot.saveList(list); msg != "" {
+		list.items = previous
+		return notice + msg
+	}
+	r.Last = time.Now()
+	if err := bot.saveRecurrences(); err != nil {
+		log.Printf("[ERROR ListBot cannot save recurrences] Workspace {%s} Error {%s}", bot.workspace, err)
+	}
+	bot.armReminder()
+	return notice + bot.tr("List '%s' was reset (%s)", list.name, bot.describe(r))
+}
+
+// repeat sets, replaces or removes ("off") the recurrence of a list. Only
+// the chat owning a shared list can make it repeat, otherwise the list would
+// be reset once per chat.
+func (lb *ListBot) repeat(s string) string {
+	args := reListRepeat.FindStringSubmatch(s)
+	l, ok := lb.findList(args[1])
+	if !ok {
+		return lb.tr("Invalid list name: %s", unquote(args[1]))
+	}
+	if l.linked {
+		return lb.tr("List '%s' belongs to another chat, only that chat can make it repeat", l.name)
+	}
+	spec := strings.TrimSpace(args[2])
+	if strings.EqualFold(spec, "off") {
+		if _, ok := lb.recurrences[l.id]; !ok {
+			return lb.tr("List '%s' does not repeat", l.name)
+		}
+		lb.stopRecurrence(l.id)
+		return lb.tr("List '%s' does not repeat anymore", l.name)
+	}
+	schedule, err := gotto.ParseSchedule(spec)
+	if err != nil {
+		return lb.tr("Invalid schedule: %s. Use \"every <duration>\", \"daily <hh:mm>\" or \"<weekday> <hh:mm>\"", spec)
+	}
+	r := &recurrence{Schedule: schedule.String(), Last: time.Now(), id: l.id}
+	if args[3] != "" {
+		template, err := lb.loadTemplate(args[3])
+		if err != nil || template == nil {
+			return lb.tr("Invalid template name: %s", unquote(args[3]))
+		}
+		r.Template = template.name
+	}
+	lb.stopRecurrence(l.id)
+	if err := lb.armRecurrence(r); err != nil {
+		return lb.abort("schedule list", l.name, err)
+	}
+	lb.recurrences[l.id] = r
+	if err := lb.saveRecurrences(); err != nil {
+		return lb.abort("save the schedule of list", l.name, err)
+	}
+	return lb.tr("List '%s' will be reset %s, next on %s", l.name, lb.describe(r), schedule.Next(time.Now()).Format(dueLayout))
+}
+
+func (lb *ListBot) listRepeats(s string) string {
+	if len(lb.recurrences) == 0 {
+		return lb.tr("No repeating lists. Set one with /list repeat <name> <schedule>")
+	}
+	lines := []string{}
+	for id, r := range lb.recurrences {
+		name := id
+		if l, ok := lb.lists[id]; ok {
+			name = l.name
+		}
+		lines = append(lines, fmt.Sprintf("- %s: %s", name, lb.describe(r)))
+	}
+	sort.Strings(lines)
+	return lb.tr("Repeating lists:") + "\n" + strings.Join(lines, "\n")
+}
@@ -0,0 +1,152 @@
+package gottolists
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gvisco/vi.sco/pkg/gotto"
+)
+
+// remindersFileName stores when the chat was last reminded of its due items,
+// so that the reminders missed while the bot was down are sent on restart.
+const remindersFileName string = "listbot.reminders"
+
+const snoozeCallback string = "lists:snooze:"
+
+// sharedReminderCheck is how often the reminders of a chat with shared lists
+// are checked, as their due items can be changed by other chats.
+const sharedReminderCheck time.Duration = 10 * time.Minute
+
+type reminders struct {
+	Last time.Time
+}
+
+func (bot *ListBot) loadReminders() {
+	bot.lastReminder = time.Now()
+	data, err := ioutil.ReadFile(filepath.Join(bot.workspace, remindersFileName))
+	if os.IsNotExist(err) {
+		return
+	}
+	r := reminders{}
+	if err == nil {
+		err = json.Unmarshal(data, &r)
+	}
+	if err != nil {
+		log.Printf("[ERROR ListBot cannot read reminders] Workspace {%s} Error {%s}", bot.workspace, err)
+		return
+	}
+	bot.lastReminder = r.Last
+}
+
+func (bot *ListBot) saveReminders() error {
+	data, err := json.Marshal(reminders{Last: bot.lastReminder})
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(bot.workspace, remindersFileName), data, 0644)
+}
+
+// armReminder schedules the reminder of the next due item, if any.
+func (bot *ListBot) armReminder() {
+	if bot.reminder != nil {
+		bot.reminder.Stop()
+		bot.reminder = nil
+	}
+	var next time.Time
+	if due := dueItems(bot.sortedLists(), bot.lastReminder, time.Time{}); len(due) > 0 {
+		next = *due[0].item.Due
+	}
+	if bot.hasShared() {
+		if check := time.Now().Add(sharedReminderCheck); next.IsZero() || next.After(check) {
+			next = check
+		}
+	}
+	if next.IsZero() {
+		return
+	}
+	bot.reminder = bot.conversation.At(next, bot.remind)
+}
+
+// remind sends a message, with a button to snooze it, for each item due
+// since the last reminder. The messages are sent after releasing sharedMu,
+// so that a slow network does not hold up the other chats.
+func (bot *ListBot) remind() string {
+	notice, reminders := bot.dueReminders()
+	for _, reply := range reminders {
+		if _, err := bot.conversation.Send(reply); err != nil {
+			log.Printf("[ERROR ListBot Cannot send reminder] Workspace {%s} Reminder {%s} Error {%s} ", bot.workspace, summary(reply.Text), err)
+		}
+	}
+	return notice
+}
+
+// dueReminders collects the reminders of the items due since the last ones
+// and schedules the next.
+func (bot *ListBot) dueReminders() (string, []*gotto.Reply) {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	bot.reminder = nil
+	notice := bot.refreshShared()
+	now := time.Now()
+	reminders := []*gotto.Reply{}
+	for _, d := range dueItems(bot.sortedLists(), bot.lastReminder, now) {
+		reply := &gotto.Reply{Text: bot.tr("%s '%s' is due (%s, list '%s')", dueMark, d.item.Text, d.item.Due.Format(dueLayout), d.list.name)}
+		if data := itemData(snoozeCallback, d.index, d.item, d.list.id); len(data) <= maxCallbackData {
+			label := bot.tr("Snooze %s", shortDuration(bot.config.Snooze))
+			reply.Keyboard = [][]gotto.Button{{{Text: label, Data: data}}}
+		}
+		reminders = append(reminders, reply)
+	}
+	bot.lastReminder = now
+	if err := bot.saveReminders(); err != nil {
+		log.Printf("[ERROR ListBot cannot save reminders] Workspace {%s} Error {%s}", bot.workspace, err)
+	}
+	bot.armReminder()
+	return notice, reminders
+}
+
+// shortDuration formats a duration without the trailing zero units, e.g.
+// "1h" instead of "1h0m0s".
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// snooze postpones the due date of an item, replying with the new text of
+// the reminder. As for toggle, the item must still be the one of the button.
+func (bot *ListBot) snooze(args []string) *gotto.Reply {
+	list, ok := bot.lists[args[2]]
+	if !ok {
+		return &gotto.Reply{Text: bot.tr("Invalid list: %s", args[2])}
+	}
+	if bot.deniedButton(list) {
+		return nil
+	}
+	idx, err := strconv.Atoi(args[0])
+	if err != nil || idx < 0 || idx >= len(list.items) || list.items[idx].Done || list.items[idx].Due == nil || list.items[idx].fingerprint() != args[1] {
+		return &gotto.Reply{Text: bot.tr("This reminder is not valid anymore")}
+	}
+	item := &list.items[idx]
+	previous := item.Due
+	due := time.Now().Add(bot.config.Snooze).Truncate(time.Minute)
+	item.Due = &due
+	bot.action = fmt.Sprintf("snooze [%d] %s", idx, summary(item.Text))
+	if msg := bot.saveList(list); msg != "" {
+		item.Due = previous
+		return &gotto.Reply{Text: msg}
+	}
+	return &gotto.Reply{Text: bot.tr("%s '%s' snoozed until %s", dueMark, item.Text, due.Format(dueLayout))}
+}
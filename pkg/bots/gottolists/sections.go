@@ -0,0 +1,154 @@
+package gottolists
+
+import (
+	"strings"
+)
+
+// Lists are trees stored flat: sections are headers grouping the items
+// following them, and every item has an indentation level making it a child
+// of the closest previous item with a lower level.
+
+const sectionMark string = "▸"
+
+// maxLevel is the deepest indentation level
+const maxLevel int = 4
+
+// indentation is the prefix of an item at a given depth in views
+const indentation string = "    "
+
+// maxLevelAfter is the deepest level allowed to an item following the given
+// one: a child of it, or a top level item after a section.
+func maxLevelAfter(previous *Item) int {
+	if previous == nil || previous.Section {
+		return 0
+	}
+	if previous.Level >= maxLevel {
+		return maxLevel
+	}
+	return previous.Level + 1
+}
+
+// normalize fixes the levels left invalid by a change, e.g. the children of
+// a removed item, and returns whether anything was changed.
+func (list *List) normalize() bool {
+	changed := false
+	var previous *Item
+	for idx := range list.items {
+		item := &list.items[idx]
+		max := maxLevelAfter(previous)
+		if item.Section {
+			max = 0
+		}
+		if item.Level > max {
+			item.Level = max
+			changed = true
+		}
+		previous = item
+	}
+	return changed
+}
+
+// depth is how much an item is indented in views: items within a section
+// are indented one more level than the section.
+func (list *List) depth(index int) int {
+	if list.items[index].Section {
+		return 0
+	}
+	for idx := index - 1; idx >= 0; idx-- {
+		if list.items[idx].Section {
+			return list.items[index].Level + 1
+		}
+	}
+	return list.items[index].Level
+}
+
+// subtree returns the position of the last descendant of an item.
+func (list *List) subtree(index int) int {
+	last := index
+	for last+1 < len(list.items) && !list.items[last+1].Section && list.items[last+1].Level > list.items[index].Level {
+		last++
+	}
+	return last
+}
+
+// reorder applies a permutation to the top level items of every section,
+// each moved with its descendants, so that the tree is preserved.
+func (list *List) reorder(permute func(units [][]Item)) {
+	result := make([]Item, 0, len(list.items))
+	units := [][]Item{}
+	flush := func() {
+		permute(units)
+		for _, unit := range units {
+			result = append(result, unit...)
+		}
+		units = [][]Item{}
+	}
+	for idx := 0; idx < len(list.items); {
+		if list.items[idx].Section {
+			flush()
+			result = append(result, list.items[idx])
+			idx++
+			continue
+		}
+		last := list.subtree(idx)
+		units = append(units, list.items[idx:last+1])
+		idx = last + 1
+	}
+	flush()
+	list.items = result
+}
+
+// addSection appends a section header to the bottom of the list.
+func (lb *ListBot) addSection(list *List, text string) string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return lb.tr("Invalid section name")
+	}
+	list.addItem(Item{Text: text, Section: true})
+	return lb.saveList(list)
+}
+
+// indentItems makes a range of items, with their descendants, children of
+// the item preceding them.
+func (lb *ListBot) indentItems(list *List, pos string) string {
+	first, last, ok := parseRange(list, pos)
+	if !ok {
+		return lb.tr("Invalid index %s", pos)
+	}
+	var previous *Item
+	if first > 0 {
+		previous = &list.items[first-1]
+	}
+	if list.items[first].Section || list.items[first].Level+1 > maxLevelAfter(previous) {
+		return lb.tr("Item %d cannot be indented further", first)
+	}
+	end := list.subtree(last)
+	for idx := first; idx <= end; idx++ {
+		if list.items[idx].Section {
+			break
+		}
+		list.items[idx].Level++
+	}
+	return lb.saveList(list)
+}
+
+// outdentItems moves a range of items, with their descendants, one level up.
+func (lb *ListBot) outdentItems(list *List, pos string) string {
+	first, last, ok := parseRange(list, pos)
+	if !ok {
+		return lb.tr("Invalid index %s", pos)
+	}
+	if list.items[first].Level == 0 {
+		return lb.tr("Item %d is not indented", first)
+	}
+	end := list.subtree(last)
+	for idx := first; idx <= end; idx++ {
+		if list.items[idx].Section {
+			break
+		}
+		if list.items[idx].Level > 0 {
+			list.items[idx].Level--
+		}
+	}
+	return lb.saveList(list)
+}
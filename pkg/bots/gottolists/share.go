@@ -0,0 +1,288 @@
+package gottolists
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// sharesFileName is the registry of the shared lists, in the parent directory
+// of the chat workspaces since it is used by all the chats.
+const sharesFileName string = "shares.json"
+
+const linkFileExt string = ".link"
+
+// sharedMu guards the share registry and the files of the shared lists, which
+// can be changed by several conversations: the ListBots hold it while
+// handling an update.
+var sharedMu sync.Mutex
+
+// notSharedNotice tells a chat that a list it joined was unshared.
+const notSharedNotice string = "List '%s' is not shared with this chat anymore"
+
+// shareError is a failure of sharing which the user can act upon. It is
+// translated where the reply is built, see ListBot.errorText.
+type shareError struct {
+	format string
+	args   []interface{}
+}
+
+func newShareError(format string, args ...interface{}) error {
+	return &shareError{format: format, args: args}
+}
+
+func (e *shareError) Error() string {
+	return fmt.Sprintf(e.format, e.args...)
+}
+
+// errorText prints an error for the user, in their language if it is a
+// shareError.
+func (lb *ListBot) errorText(err error) string {
+	if e, ok := err.(*shareError); ok {
+		return lb.tr(e.format, e.args...)
+	}
+	return err.Error()
+}
+
+// share is a list made available to other chats through its token.
+type share struct {
+	Path  string
+	Owner string
+}
+
+// link is the content of the file which joins a shared list into a chat.
+type link struct {
+	Token string
+}
+
+func sharesPath(workspace string) string {
+	return filepath.Join(filepath.Dir(workspace), sharesFileName)
+}
+
+func loadShares(workspace string) (map[string]share, error) {
+	shares := make(map[string]share)
+	data, err := ioutil.ReadFile(sharesPath(workspace))
+	if os.IsNotExist(err) {
+		return shares, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &shares); err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+func saveShares(workspace string, shares map[string]share) error {
+	data, err := json.MarshalIndent(shares, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(sharesPath(workspace), data, 0644)
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return strings.ToUpper(hex.EncodeToString(b)), nil
+}
+
+// tokenOf returns the token of the share of a list file, if any.
+func tokenOf(shares map[string]share, path string) string {
+	for token, s := range shares {
+		if s.Path == path {
+			return token
+		}
+	}
+	return ""
+}
+
+func (bot *ListBot) linkPath(id string) string {
+	return filepath.Join(bot.workspace, id+linkFileExt)
+}
+
+// loadLinks reads the shared lists joined into the workspace. Links to lists
+// which are not shared anymore are removed.
+func (bot *ListBot) loadLinks(files []os.FileInfo) {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	shares, err := loadShares(bot.workspace)
+	if err != nil {
+		log.Printf("[ERROR ListBot cannot read shares] Workspace {%s} Error {%s}", bot.workspace, err)
+		return
+	}
+	for _, list := range bot.lists {
+		list.token = tokenOf(shares, list.filePath)
+	}
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), linkFileExt) {
+			continue
+		}
+		id := strings.TrimSuffix(file.Name(), linkFileExt)
+		list, err := bot.openLink(id, shares)
+		if err != nil {
+			log.Printf("[ListBot removing link] Workspace {%s} Link {%s} Reason {%s}", bot.workspace, file.Name(), err)
+			os.Remove(bot.linkPath(id))
+			bot.conversation.Unpin(pinKey(id), nil)
+			continue
+		}
+		bot.lists[id] = list
+	}
+}
+
+func (bot *ListBot) openLink(id string, shares map[string]share) (*List, error) {
+	data, err := ioutil.ReadFile(bot.linkPath(id))
+	if err != nil {
+		return nil, err
+	}
+	l := link{}
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, err
+	}
+	s, ok := shares[l.Token]
+	if !ok {
+		return nil, newShareError("list not shared anymore")
+	}
+	list := &List{id: id, name: id, filePath: s.Path, token: l.Token, linked: true}
+	if err := list.loadFromFile(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (bot *ListBot) hasShared() bool {
+	for _, list := range bot.lists {
+		if list.token != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// refreshShared reloads the shared lists changed by other chats and drops
+// the joined ones which are not shared anymore, returning a notice for the
+// user if any.
+func (bot *ListBot) refreshShared() string {
+	shares, err := loadShares(bot.workspace)
+	if err != nil {
+		log.Printf("[ERROR ListBot cannot read shares] Workspace {%s} Error {%s}", bot.workspace, err)
+		return ""
+	}
+	notice := ""
+	for id, list := range bot.lists {
+		if list.token == "" {
+			continue
+		}
+		if _, ok := shares[list.token]; !ok && list.linked {
+			os.Remove(bot.linkPath(id))
+			bot.unpinList(list, nil)
+			bot.stopRecurrence(id)
+			delete(bot.lists, id)
+			if bot.currentList == list {
+				bot.state.Set(waiting)
+			}
+			notice += bot.tr(notSharedNotice, list.name) + "\n"
+			continue
+		}
+		if err := list.refresh(); err != nil {
+			log.Printf("[ERROR ListBot cannot reload shared list] Workspace {%s} File {%s} Error {%s}", bot.workspace, list.filePath, err)
+		}
+	}
+	return notice
+}
+
+// shareList shares a list owned by the chat, returning its token.
+func (lb *ListBot) shareList(list *List, userId string) (string, error) {
+	if list.linked {
+		return "", newShareError("list '%s' belongs to another chat", list.name)
+	}
+	if list.token != "" {
+		return list.token, nil
+	}
+	shares, err := loadShares(lb.workspace)
+	if err != nil {
+		return "", err
+	}
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	shares[token] = share{Path: list.filePath, Owner: userId}
+	if err := saveShares(lb.workspace, shares); err != nil {
+		return "", err
+	}
+	list.token = token
+	return token, nil
+}
+
+// unshareList revokes the token of a list. Only the user who shared it can.
+func (lb *ListBot) unshareList(list *List, userId string) error {
+	if list.linked {
+		return newShareError("list '%s' belongs to another chat", list.name)
+	}
+	shares, err := loadShares(lb.workspace)
+	if err != nil {
+		return err
+	}
+	if s, ok := shares[list.token]; ok && s.Owner != userId {
+		return newShareError("only the user who shared list '%s' can stop sharing it", list.name)
+	}
+	return lb.revokeShare(list)
+}
+
+func (lb *ListBot) revokeShare(list *List) error {
+	shares, err := loadShares(lb.workspace)
+	if err != nil {
+		return err
+	}
+	delete(shares, list.token)
+	if err := saveShares(lb.workspace, shares); err != nil {
+		return err
+	}
+	list.token = ""
+	return nil
+}
+
+// joinList links a shared list into the chat.
+func (lb *ListBot) joinList(token string) (*List, error) {
+	shares, err := loadShares(lb.workspace)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := shares[token]
+	if !ok {
+		return nil, newShareError("invalid token %s", token)
+	}
+	for _, l := range lb.lists {
+		if l.filePath == s.Path {
+			return nil, newShareError("list '%s' is already available in this chat", l.name)
+		}
+	}
+	list := &List{filePath: s.Path, token: token, linked: true}
+	if err := list.loadFromFile(); err != nil {
+		return nil, err
+	}
+	list.id = slugify(list.name)
+	if other, ok := lb.lists[list.id]; ok {
+		return nil, newShareError("a list with name '%s' already exists, rename it first", other.name)
+	}
+	data, err := json.Marshal(link{Token: token})
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(lb.linkPath(list.id), data, 0644); err != nil {
+		return nil, err
+	}
+	lb.lists[list.id] = list
+	return list, nil
+}
@@ -0,0 +1,84 @@
+package gottolists
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+)
+
+const stateFileName string = "listbot.state"
+
+// savedState is the on-disk representation of the conversation state, so that
+// a restart does not interrupt an ongoing modal interaction.
+type savedState struct {
+	State   string
+	List    string
+	Updated time.Time
+}
+
+func parseState(name string) (state, bool) {
+	for _, s := range listMachine.States() {
+		if s.String() == name {
+			return s.(state), true
+		}
+	}
+	return waiting, false
+}
+
+// saveState persists the state when it changes. The modal states are saved
+// at every input, as their expiry counts from the last one, while the
+// updates handled in Waiting leave the file untouched.
+func (bot *ListBot) saveState() error {
+	saved := savedState{State: bot.state.Current().String(), Updated: time.Now()}
+	if saved.State == waiting.String() && bot.saved.State == saved.State {
+		return nil
+	}
+	if bot.currentList != nil {
+		saved.List = bot.currentList.name
+	}
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(bot.workspace+"/"+stateFileName, data, 0644); err != nil {
+		return err
+	}
+	bot.saved = saved
+	return nil
+}
+
+// restoreState brings the bot back to the state saved before the last
+// shutdown, unless it is older than the configured expiry or refers to a list
+// which does not exist anymore.
+func (bot *ListBot) restoreState(expiry time.Duration) {
+	data, err := ioutil.ReadFile(bot.workspace + "/" + stateFileName)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[ERROR ListBot cannot read state] Workspace {%s} Error {%s}", bot.workspace, err)
+		}
+		return
+	}
+	saved := savedState{}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		log.Printf("[ERROR ListBot cannot decode state] Workspace {%s} Error {%s}", bot.workspace, err)
+		return
+	}
+	current, ok := parseState(saved.State)
+	if !ok || current == waiting {
+		return
+	}
+	if expiry > 0 && time.Since(saved.Updated) > expiry {
+		log.Printf("[ListBot abandoning expired state] Workspace {%s} State {%s} Updated {%s}", bot.workspace, saved.State, saved.Updated)
+		return
+	}
+	list, ok := bot.findList(saved.List)
+	if !ok {
+		log.Printf("[ListBot abandoning state on missing list] Workspace {%s} State {%s} ListName {%s}", bot.workspace, saved.State, saved.List)
+		return
+	}
+	log.Printf("[ListBot restoring state] Workspace {%s} State {%s} ListName {%s}", bot.workspace, saved.State, saved.List)
+	bot.state.Set(current)
+	bot.currentList = list
+}
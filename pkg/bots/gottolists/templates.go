@@ -0,0 +1,134 @@
+package gottolists
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Templates are stored like lists, with a different extension so that they
+// are not loaded as lists.
+const templateFileExt string = ".template"
+
+func (lb *ListBot) templatePath(id string) string {
+	return filepath.Join(lb.workspace, id+templateFileExt)
+}
+
+// loadTemplate reads the template with the given name, returning nil if it
+// does not exist.
+func (lb *ListBot) loadTemplate(name string) (*List, error) {
+	id := slugify(unquote(name))
+	if id == "" {
+		return nil, nil
+	}
+	template := &List{id: id, name: unquote(name), filePath: lb.templatePath(id)}
+	if err := template.loadFromFile(); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return template, nil
+}
+
+// templateItems copies the items of a template, which are never checked and
+// have no due date, keeping its sections and indentation.
+func (template *List) templateItems() []Item {
+	items := make([]Item, 0, len(template.items))
+	for _, item := range template.items {
+		items = append(items, Item{Text: item.Text, Level: item.Level, Section: item.Section})
+	}
+	return items
+}
+
+// hasTemplate guards /list new <name> from <template>, so that lists whose
+// name contains "from" can still be created when there is no such template.
+func hasTemplate(ctx interface{}, s string) bool {
+	args := reNewFromTemplate.FindStringSubmatch(s)
+	if args == nil {
+		return false
+	}
+	template, err := ctx.(*ListBot).loadTemplate(args[2])
+	return err == nil && template != nil
+}
+
+func (lb *ListBot) saveTemplate(s string) string {
+	lname := reTemplateSave.FindStringSubmatch(s)[1]
+	l, ok := lb.findList(lname)
+	if !ok {
+		return lb.tr("Invalid list name: %s", unquote(lname))
+	}
+	template := &List{id: l.id, name: l.name, filePath: lb.templatePath(l.id), items: l.templateItems()}
+	if err := template.saveToFile(); err != nil {
+		return lb.abort("save template", l.name, err)
+	}
+	return lb.tr("Template '%s' saved with %d items. Use it with:\n/list new <name> from %s", l.name, len(template.items), l.name)
+}
+
+func (lb *ListBot) deleteTemplate(s string) string {
+	tname := unquote(reTemplateDel.FindStringSubmatch(s)[1])
+	id := slugify(tname)
+	if id == "" {
+		return lb.tr("Invalid template name: %s", tname)
+	}
+	if err := os.Remove(lb.templatePath(id)); err != nil {
+		if os.IsNotExist(err) {
+			return lb.tr("Invalid template name: %s", tname)
+		}
+		return lb.abort("delete template", tname, err)
+	}
+	return lb.tr("Template '%s' deleted", tname)
+}
+
+func (lb *ListBot) listTemplates(s string) string {
+	files, err := ioutil.ReadDir(lb.workspace)
+	if err != nil {
+		log.Printf("[ERROR ListBot Cannot read templates] Workspace {%s} Error {%s} ", lb.workspace, err)
+		return lb.tr("Cannot read the templates. An error occurred")
+	}
+	names := []string{}
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), templateFileExt) {
+			continue
+		}
+		template := &List{filePath: filepath.Join(lb.workspace, file.Name())}
+		if err := template.loadFromFile(); err != nil {
+			log.Printf("[ERROR ListBot cannot read template from file] File {%s} Error {%s}", template.filePath, err)
+			continue
+		}
+		if template.name == "" {
+			template.name = strings.TrimSuffix(file.Name(), templateFileExt)
+		}
+		names = append(names, template.name)
+	}
+	if len(names) == 0 {
+		return lb.tr("No templates. Save one with /list template save <name>")
+	}
+	sort.Strings(names)
+	return lb.tr("Your templates:") + "\n- " + strings.Join(names, "\n- ")
+}
+
+// newFromTemplate creates a list with the items of a template.
+func (lb *ListBot) newFromTemplate(s string) string {
+	args := reNewFromTemplate.FindStringSubmatch(s)
+	lname := unquote(args[1])
+	if l, ok := lb.findList(lname); ok {
+		return lb.tr("A list with name '%s' already exists", l.name)
+	}
+	template, err := lb.loadTemplate(args[2])
+	if err != nil || template == nil {
+		return lb.tr("Invalid template name: %s", unquote(args[2]))
+	}
+	list, msg := lb.createList(lname)
+	if list == nil {
+		return msg
+	}
+	list.items = template.templateItems()
+	if msg := lb.saveList(list); msg != "" {
+		return msg
+	}
+	return list.renderPage(lb.locale(), 0, lb.config.PageSize)
+}
@@ -0,0 +1,166 @@
+package gottolists
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Deleted lists are moved, with their history, to the trash directory of
+// the workspace, where they are kept for Config.TrashRetention. The time of
+// the deletion is the modification time of the list file.
+const trashDirName string = "trash"
+
+func (lb *ListBot) trashPath(fileName string) string {
+	return filepath.Join(lb.workspace, trashDirName, fileName)
+}
+
+// moveToTrash moves the files of a list to the trash, replacing a trashed
+// list with the same name.
+func (lb *ListBot) moveToTrash(list *List) error {
+	if err := os.MkdirAll(filepath.Join(lb.workspace, trashDirName), 0755); err != nil {
+		return err
+	}
+	trashed := lb.trashPath(list.id + listFileExt)
+	if err := os.Rename(list.filePath, trashed); err != nil {
+		return err
+	}
+	now := time.Now()
+	if err := os.Chtimes(trashed, now, now); err != nil {
+		log.Printf("[ERROR ListBot Cannot set deletion time] Workspace {%s} ListName {%s} Error {%s} ", lb.workspace, list.name, err)
+	}
+	journal := lb.trashPath(list.id + journalFileExt)
+	if err := os.Rename(list.journalPath(), journal); err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[ERROR ListBot Cannot move list history to the trash] Workspace {%s} ListName {%s} Error {%s} ", lb.workspace, list.name, err)
+		}
+		os.Remove(journal)
+	}
+	return nil
+}
+
+// trashedList is a list in the trash.
+type trashedList struct {
+	*List
+	deleted time.Time
+}
+
+// trashed reads the lists in the trash, sorted by name.
+func (lb *ListBot) trashed() ([]trashedList, error) {
+	files, err := ioutil.ReadDir(filepath.Join(lb.workspace, trashDirName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := []trashedList{}
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), listFileExt) {
+			continue
+		}
+		id := strings.TrimSuffix(file.Name(), listFileExt)
+		list := &List{id: id, name: id, filePath: lb.trashPath(file.Name())}
+		if err := list.loadFromFile(); err != nil {
+			log.Printf("[ERROR ListBot cannot read list from file] File {%s} Error {%s}", list.filePath, err)
+			continue
+		}
+		result = append(result, trashedList{List: list, deleted: file.ModTime()})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].id < result[j].id })
+	return result, nil
+}
+
+// purgeTrash removes the lists kept in the trash for longer than the
+// retention, then schedules the next purge.
+func (bot *ListBot) purgeTrash() string {
+	if bot.purge != nil {
+		bot.purge.Stop()
+		bot.purge = nil
+	}
+	if bot.config.TrashRetention <= 0 {
+		return ""
+	}
+	lists, err := bot.trashed()
+	if err != nil {
+		log.Printf("[ERROR ListBot Cannot read the trash] Workspace {%s} Error {%s} ", bot.workspace, err)
+		return ""
+	}
+	var next time.Time
+	for _, l := range lists {
+		expiry := l.deleted.Add(bot.config.TrashRetention)
+		if expiry.After(time.Now()) {
+			if next.IsZero() || expiry.Before(next) {
+				next = expiry
+			}
+			continue
+		}
+		log.Printf("[ListBot purging list] Workspace {%s} ListName {%s} Deleted {%s}", bot.workspace, l.name, l.deleted)
+		if err := os.Remove(l.filePath); err != nil {
+			log.Printf("[ERROR ListBot Cannot purge list] Workspace {%s} ListName {%s} Error {%s} ", bot.workspace, l.name, err)
+		}
+		if err := os.Remove(l.journalPath()); err != nil && !os.IsNotExist(err) {
+			log.Printf("[ERROR ListBot Cannot purge list history] Workspace {%s} ListName {%s} Error {%s} ", bot.workspace, l.name, err)
+		}
+	}
+	if !next.IsZero() {
+		bot.purge = bot.conversation.At(next, bot.purgeTrash)
+	}
+	return ""
+}
+
+func (lb *ListBot) listTrash(s string) string {
+	lists, err := lb.trashed()
+	if err != nil {
+		log.Printf("[ERROR ListBot Cannot read the trash] Workspace {%s} Error {%s} ", lb.workspace, err)
+		return lb.tr("Cannot read the trash. An error occurred")
+	}
+	if len(lists) == 0 {
+		return lb.tr("The trash is empty")
+	}
+	var b strings.Builder
+	b.WriteString(lb.tr("Deleted lists:"))
+	for _, l := range lists {
+		b.WriteString("\n" + lb.tr("- %s (%d items), deleted on %s", l.name, len(l.items), l.deleted.Format(dueLayout)))
+		if lb.config.TrashRetention > 0 {
+			b.WriteString(lb.tr(", purged on %s", l.deleted.Add(lb.config.TrashRetention).Format(dueLayout)))
+		}
+	}
+	b.WriteString("\n" + lb.tr("Restore one with /list restore <name>"))
+	return b.String()
+}
+
+// restore moves a list, with its history, back from the trash.
+func (lb *ListBot) restore(s string) string {
+	lname := unquote(reListRestore.FindStringSubmatch(s)[1])
+	id := slugify(lname)
+	if l, ok := lb.lists[id]; ok {
+		return lb.tr("A list with name '%s' already exists, rename it before restoring", l.name)
+	}
+	trashed := &List{id: id, name: lname, filePath: lb.trashPath(id + listFileExt)}
+	if err := trashed.loadFromFile(); err != nil {
+		if os.IsNotExist(err) {
+			return lb.tr("List '%s' is not in the trash", lname)
+		}
+		return lb.abort("read deleted list", lname, err)
+	}
+	if msg := lb.checkPermission(trashed, owner, false); msg != "" {
+		return msg
+	}
+	list := &List{id: id, name: trashed.name, filePath: filepath.Join(lb.workspace, id+listFileExt)}
+	if err := os.Rename(trashed.filePath, list.filePath); err != nil {
+		return lb.abort("restore list", list.name, err)
+	}
+	if err := os.Rename(trashed.journalPath(), list.journalPath()); err != nil && !os.IsNotExist(err) {
+		log.Printf("[ERROR ListBot Cannot restore list history] Workspace {%s} ListName {%s} Error {%s} ", lb.workspace, list.name, err)
+	}
+	if err := list.loadFromFile(); err != nil {
+		return lb.abort("restore list", list.name, err)
+	}
+	lb.lists[id] = list
+	return lb.tr("List '%s' restored with %d items", list.name, len(list.items))
+}
@@ -0,0 +1,196 @@
+package gotto
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+const backupPrefix string = "workspace-"
+const backupSuffix string = ".tar.gz"
+const backupTimeLayout string = "20060102-150405"
+
+// Backup writes a compressed snapshot of the whole workspace root to the
+// configured backup directory and returns the path of the archive.
+func (engine *Gotto) Backup() (string, error) {
+	return snapshot(workspaceRoot, engine.config.Backup.Dir, time.Now())
+}
+
+func snapshot(root string, dir string, now time.Time) (string, error) {
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return "", fmt.Errorf("cannot create backup dir - %s", err)
+	}
+	tmp, err := ioutil.TempFile(dir, ".backup-*")
+	if err != nil {
+		return "", fmt.Errorf("cannot create backup file - %s", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	gz := gzip.NewWriter(tmp)
+	tw := tar.NewWriter(gz)
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		// skip the files removed by the bots during the walk
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() && !info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		// the bots keep writing during the backup: read the files at once, so
+		// that the size in the header matches the content
+		var data []byte
+		if info.Mode().IsRegular() {
+			data, err = ioutil.ReadFile(path)
+			if os.IsNotExist(err) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		header.Size = int64(len(data))
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err = tw.Write(data)
+		return err
+	})
+	if err == nil {
+		err = tw.Close()
+	}
+	if err == nil {
+		err = gz.Close()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot write backup archive - %s", err)
+	}
+
+	target := filepath.Join(dir, backupPrefix+now.Format(backupTimeLayout)+backupSuffix)
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return "", fmt.Errorf("cannot store backup archive - %s", err)
+	}
+	return target, nil
+}
+
+// prune deletes the backups in dir that are not among the newest copy of
+// each of the last keepDaily days or the last keepWeekly ISO weeks. The newest
+// backup is always kept.
+func prune(dir string, keepDaily int, keepWeekly int) ([]string, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	type backup struct {
+		name string
+		time time.Time
+	}
+	backups := []backup{}
+	for _, file := range files {
+		name := file.Name()
+		if !strings.HasPrefix(name, backupPrefix) || !strings.HasSuffix(name, backupSuffix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, backupPrefix), backupSuffix)
+		t, err := time.ParseInLocation(backupTimeLayout, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{name: name, time: t})
+	}
+	// newest first
+	sort.Slice(backups, func(i, j int) bool { return backups[i].time.After(backups[j].time) })
+
+	keep := make(map[string]bool)
+	days := make(map[string]bool)
+	weeks := make(map[string]bool)
+	if len(backups) > 0 {
+		keep[backups[0].name] = true
+	}
+	for _, b := range backups {
+		day := b.time.Format("2006-01-02")
+		if !days[day] && len(days) < keepDaily {
+			days[day] = true
+			keep[b.name] = true
+		}
+		year, week := b.time.ISOWeek()
+		wk := fmt.Sprintf("%d-%d", year, week)
+		if !weeks[wk] && len(weeks) < keepWeekly {
+			weeks[wk] = true
+			keep[b.name] = true
+		}
+	}
+
+	removed := []string{}
+	for _, b := range backups {
+		if keep[b.name] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, b.name)); err != nil {
+			return removed, err
+		}
+		removed = append(removed, b.name)
+	}
+	return removed, nil
+}
+
+func (engine *Gotto) runBackup() error {
+	path, err := engine.Backup()
+	if err != nil {
+		return err
+	}
+	log.Printf("[Backup created] File {%s}", path)
+	removed, err := prune(engine.config.Backup.Dir, engine.config.Backup.KeepDaily, engine.config.Backup.KeepWeekly)
+	if err != nil {
+		return fmt.Errorf("cannot prune old backups - %s", err)
+	}
+	for _, name := range removed {
+		log.Printf("[Backup removed] File {%s}", name)
+	}
+	return nil
+}
+
+func (engine *Gotto) scheduleBackups(sched *Schedule) {
+	for {
+		next := sched.Next(time.Now())
+		time.Sleep(time.Until(next))
+		if err := engine.runBackup(); err != nil {
+			log.Printf("[ERROR Backup failed] Error {%s}", err)
+			engine.alertAdmins(fmt.Sprintf("Scheduled backup failed: %s", err))
+		}
+	}
+}
+
+func (engine *Gotto) alertAdmins(text string) {
+	for _, id := range engine.config.Permissions.Admins {
+		msg := tgbotapi.NewMessage(int64(id), text)
+		if _, err := engine.tgbot.Send(msg); err != nil {
+			log.Printf("[ERROR Cannot alert admin] UserId {%d} Error {%s}", id, err)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// Package fsm implements finite-state machines for conversational bots.
+//
+// A Machine describes states, transitions and actions once; every conversation
+// then drives its own Instance, bound to a context value (usually the bot)
+// which is passed to guards and actions.
+package fsm
+
+import (
+	"fmt"
+	"log"
+)
+
+// State identifies a node of a machine. Any comparable type with a String
+// method can be used, typically an int enum.
+type State interface {
+	String() string
+}
+
+// Guard reports whether a transition can be taken for the given input.
+type Guard func(ctx interface{}, input string) bool
+
+// Action is run when a state is entered or exited, or when a transition is
+// taken. It returns the text to reply with, if any.
+type Action func(ctx interface{}, input string) string
+
+// Transition connects two states. Epsilon transitions are taken without
+// consuming any input, as soon as their source state is entered.
+type Transition struct {
+	From    State
+	To      State
+	Label   string
+	Guard   Guard
+	Action  Action
+	Epsilon bool
+}
+
+type node struct {
+	state       State
+	entry       Action
+	exit        Action
+	transitions []*Transition
+}
+
+type Machine struct {
+	initial State
+	nodes   map[State]*node
+	order   []State
+}
+
+func New(initial State) *Machine {
+	return &Machine{initial: initial, nodes: make(map[State]*node)}
+}
+
+// AddState declares a state with its (optional) entry and exit actions.
+func (m *Machine) AddState(s State, entry Action, exit Action) *Machine {
+	if _, ok := m.nodes[s]; !ok {
+		m.order = append(m.order, s)
+	}
+	m.nodes[s] = &node{state: s, entry: entry, exit: exit}
+	return m
+}
+
+// On adds a transition triggered by an input for which guard holds. A nil
+// guard matches any input. Transitions are tried in declaration order.
+func (m *Machine) On(from State, label string, guard Guard, to State) *Machine {
+	return m.Add(&Transition{From: from, To: to, Label: label, Guard: guard})
+}
+
+// Then adds an epsilon transition, taken right after entering from if guard
+// holds for the input which led there. A nil guard always holds.
+func (m *Machine) Then(from State, label string, guard Guard, to State) *Machine {
+	return m.Add(&Transition{From: from, To: to, Label: label, Guard: guard, Epsilon: true})
+}
+
+func (m *Machine) Add(t *Transition) *Machine {
+	n, ok := m.nodes[t.From]
+	if !ok {
+		m.AddState(t.From, nil, nil)
+		n = m.nodes[t.From]
+	}
+	n.transitions = append(n.transitions, t)
+	return m
+}
+
+func (m *Machine) Initial() State {
+	return m.initial
+}
+
+// States returns the states in declaration order.
+func (m *Machine) States() []State {
+	return append([]State{}, m.order...)
+}
+
+// Transitions returns all the transitions, grouped by source state in
+// declaration order.
+func (m *Machine) Transitions() []*Transition {
+	result := []*Transition{}
+	for _, s := range m.order {
+		result = append(result, m.nodes[s].transitions...)
+	}
+	return result
+}
+
+// Validate checks that every transition targets a declared state and that
+// epsilon transitions cannot loop forever.
+func (m *Machine) Validate() error {
+	if _, ok := m.nodes[m.initial]; !ok {
+		return fmt.Errorf("initial state %s is not declared", m.initial)
+	}
+	for _, t := range m.Transitions() {
+		if _, ok := m.nodes[t.To]; !ok {
+			return fmt.Errorf("transition %s -> %s targets an undeclared state", t.From, t.To)
+		}
+	}
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	marks := make(map[State]int)
+	var visit func(s State, path []State) error
+	visit = func(s State, path []State) error {
+		switch marks[s] {
+		case visiting:
+			return fmt.Errorf("epsilon transitions form a cycle: %v", append(path, s))
+		case visited:
+			return nil
+		}
+		marks[s] = visiting
+		for _, t := range m.nodes[s].transitions {
+			if !t.Epsilon {
+				continue
+			}
+			if err := visit(t.To, append(path, s)); err != nil {
+				return err
+			}
+		}
+		marks[s] = visited
+		return nil
+	}
+	for _, s := range m.order {
+		if err := visit(s, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MustValidate is like Validate but panics on an invalid machine. It
+// simplifies the initialization of package-level machines.
+func (m *Machine) MustValidate() *Machine {
+	if err := m.Validate(); err != nil {
+		panic(fmt.Sprintf("fsm: invalid machine - %s", err))
+	}
+	return m
+}
+
+// Instance is a running copy of a Machine, e.g. the state of one conversation.
+type Instance struct {
+	machine *Machine
+	ctx     interface{}
+	current State
+}
+
+func (m *Machine) NewInstance(ctx interface{}) *Instance {
+	return &Instance{machine: m, ctx: ctx, current: m.initial}
+}
+
+func (i *Instance) Current() State {
+	return i.current
+}
+
+// Machine returns the machine the instance runs.
+func (i *Instance) Machine() *Machine {
+	return i.machine
+}
+
+// Set forces the current state without running any action, e.g. to restore
+// a saved state.
+func (i *Instance) Set(s State) {
+	i.current = s
+}
+
+// Fire feeds an input to the machine. The first matching transition of the
+// current state is taken, followed by any epsilon transition of the states
+// entered along the way. The replies of all the actions run are joined by
+// newlines. Inputs matching no transition are ignored.
+func (i *Instance) Fire(input string) string {
+	t := i.match(input, false)
+	if t == nil {
+		return ""
+	}
+	result := i.take(t, input)
+	// epsilon transitions were checked by Validate, this is only a safety net
+	for steps := 0; steps <= len(i.machine.nodes); steps++ {
+		t = i.match(input, true)
+		if t == nil {
+			return result
+		}
+		result = join(result, i.take(t, input))
+	}
+	log.Printf("[ERROR FSM epsilon transitions do not settle] State {%s}", i.current)
+	return result
+}
+
+func (i *Instance) match(input string, epsilon bool) *Transition {
+	for _, t := range i.machine.nodes[i.current].transitions {
+		if t.Epsilon == epsilon && (t.Guard == nil || t.Guard(i.ctx, input)) {
+			return t
+		}
+	}
+	return nil
+}
+
+func (i *Instance) take(t *Transition, input string) string {
+	log.Printf("[FSM changing state] From {%s} To {%s}", t.From, t.To)
+	result := ""
+	if exit := i.machine.nodes[t.From].exit; exit != nil {
+		result = join(result, exit(i.ctx, input))
+	}
+	if t.Action != nil {
+		result = join(result, t.Action(i.ctx, input))
+	}
+	i.current = t.To
+	if entry := i.machine.nodes[t.To].entry; entry != nil {
+		result = join(result, entry(i.ctx, input))
+	}
+	return result
+}
+
+func join(result string, reply string) string {
+	if reply == "" {
+		return result
+	}
+	if result == "" {
+		return reply
+	}
+	return result + "\n" + reply
+}
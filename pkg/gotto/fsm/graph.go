@@ -0,0 +1,50 @@
+package fsm
+
+import (
+	"fmt"
+	"strings"
+)
+
+const epsilonLabel string = "<nil>"
+
+func (t *Transition) diagramLabel() string {
+	if t.Epsilon && t.Label == "" {
+		return epsilonLabel
+	}
+	return t.Label
+}
+
+// Mermaid renders the machine as a Mermaid flowchart, one block of edges per
+// source state.
+func (m *Machine) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("graph LR\n")
+	for _, s := range m.order {
+		transitions := m.nodes[s].transitions
+		if len(transitions) == 0 {
+			continue
+		}
+		b.WriteString("\n")
+		for _, t := range transitions {
+			fmt.Fprintf(&b, "    %s -->|%s| %s\n", t.From, t.diagramLabel(), t.To)
+		}
+	}
+	return b.String()
+}
+
+// Dot renders the machine in the Graphviz DOT language. Epsilon transitions
+// are dashed and the initial state is drawn with a double border.
+func (m *Machine) Dot() string {
+	var b strings.Builder
+	b.WriteString("digraph {\n    rankdir=LR;\n")
+	fmt.Fprintf(&b, "    %q [shape=doublecircle];\n", m.initial.String())
+	for _, t := range m.Transitions() {
+		style := ""
+		if t.Epsilon {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(&b, "    %q -> %q [label=%q%s];\n", t.From.String(), t.To.String(), t.diagramLabel(), style)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -2,62 +2,422 @@ package gotto
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
+	"net/http"
 	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 	toml "github.com/pelletier/go-toml"
 )
 
+const workspaceRoot string = "./workspace"
+
 type Gotto struct {
 	tgbot         *tgbotapi.BotAPI
 	config        *Config
 	conversations map[int64]*Conversation
 	factories     []GottoBotFactory
+	backups       *Schedule
 }
 
 type GottoBotFactory interface {
-	CreateBot(workspace string) (GottoBot, error)
+	CreateBot(conversation *Conversation) (GottoBot, error)
+}
+
+// User is the Telegram user who sent an update.
+type User struct {
+	Id int
+	// UserName is the Telegram username, without "@". Not all the users
+	// have one.
+	UserName     string
+	FirstName    string
+	LastName     string
+	LanguageCode string
+}
+
+func newUser(from *tgbotapi.User) User {
+	if from == nil {
+		return User{}
+	}
+	return User{
+		Id:           from.ID,
+		UserName:     from.UserName,
+		FirstName:    from.FirstName,
+		LastName:     from.LastName,
+		LanguageCode: from.LanguageCode,
+	}
+}
+
+// String is the username of the user or, if missing, the full name.
+func (u User) String() string {
+	if u.UserName != "" {
+		return u.UserName
+	}
+	return strings.TrimSpace(u.FirstName + " " + u.LastName)
+}
+
+// Mention is "@username", which notifies the user when sent to a group, or
+// the first name of users without a username.
+func (u User) Mention() string {
+	if u.UserName != "" {
+		return "@" + u.UserName
+	}
+	return u.FirstName
 }
 
 type GottoBot interface {
-	OnUpdate(userId string, userName string, message string) string
+	OnUpdate(user User, message string) string
+}
+
+// CallbackBot is implemented by bots sending inline keyboards. OnCallback
+// receives the data of the pressed button; every bot of the conversation gets
+// it, so bots should prefix their data and ignore the rest. A non-nil reply
+// replaces the message holding the button.
+type CallbackBot interface {
+	OnCallback(user User, data string) *Reply
+}
+
+// DocumentBot is implemented by bots accepting files. OnDocument receives the
+// name and the content of a document sent to the chat.
+type DocumentBot interface {
+	OnDocument(user User, fileName string, content []byte) string
+}
+
+// maxDocumentSize is the size of the largest document passed to the bots
+const maxDocumentSize int = 1 << 20
+
+// Reply is a message with an optional inline keyboard.
+type Reply struct {
+	Text     string
+	Keyboard [][]Button
+}
+
+type Button struct {
+	Text string
+	// Data is passed to CallbackBot.OnCallback when the button is pressed.
+	// Telegram limits it to 64 bytes.
+	Data string
 }
 
 type Config struct {
 	Bot struct {
 		Token string
+		// Language is the language of the users whose Telegram language is
+		// not supported.
+		Language string
 	}
 	Permissions struct {
 		Allowed []int
+		Admins  []int
 	}
+	Backup struct {
+		Schedule   string
+		Dir        string
+		KeepDaily  int
+		KeepWeekly int
+	}
+	tree *toml.Tree
 }
 
 type Conversation struct {
 	channel   chan *tgbotapi.Update
+	events    chan func()
 	chatId    int64
 	config    *Config
 	workspace string
 	bots      []GottoBot
+	engine    *Gotto
+	// pins maps the keys of the messages pinned by the bots to their ids
+	pins   map[string]int
+	pinsMu sync.Mutex
+	// locales maps the users to the languages they chose with /lang
+	locales   map[int]string
+	localesMu sync.Mutex
+}
+
+// Timer is a delayed event scheduled with Conversation.AfterFunc,
+// Conversation.At or Conversation.Repeat.
+type Timer struct {
+	timer   *time.Timer
+	stopped bool
+}
+
+func (cc *Conversation) Workspace() string {
+	return cc.workspace
+}
+
+func (cc *Conversation) ChatId() int64 {
+	return cc.chatId
+}
+
+// AfterFunc waits for the duration to elapse and then calls f in the
+// conversation goroutine, so that it never runs concurrently with the bots'
+// OnUpdate. The reply returned by f, if any, is sent to the chat.
+func (cc *Conversation) AfterFunc(d time.Duration, f func() string) *Timer {
+	t := &Timer{}
+	t.timer = time.AfterFunc(d, func() {
+		cc.events <- func() {
+			if t.stopped {
+				return
+			}
+			t.stopped = true
+			cc.send(f())
+		}
+	})
+	return t
+}
+
+// maxTimerWait is the longest wait of the timers set with At and Repeat
+// before checking the clock again, so that they follow changes of the wall
+// clock (e.g. when the host resumes from suspension).
+const maxTimerWait time.Duration = time.Hour
+
+// At calls f in the conversation goroutine at the given time, like
+// AfterFunc. Times in the past fire as soon as possible.
+func (cc *Conversation) At(at time.Time, f func() string) *Timer {
+	t := &Timer{}
+	cc.wait(t, at, func() {
+		t.stopped = true
+		cc.send(f())
+	})
+	return t
+}
+
+// Repeat calls f in the conversation goroutine at every activation of the
+// schedule, until the returned timer is stopped. The activations missed since
+// last (e.g. while the bot was down) fire once, as soon as possible.
+func (cc *Conversation) Repeat(schedule *Schedule, last time.Time, f func() string) *Timer {
+	t := &Timer{}
+	var fire func()
+	fire = func() {
+		cc.send(f())
+		if !t.stopped {
+			cc.wait(t, schedule.Next(time.Now()), fire)
+		}
+	}
+	cc.wait(t, schedule.Next(last), fire)
+	return t
+}
+
+// wait calls fire in the conversation goroutine at the given time, unless t
+// is stopped before.
+func (cc *Conversation) wait(t *Timer, at time.Time, fire func()) {
+	d := time.Until(at)
+	if d > maxTimerWait {
+		d = maxTimerWait
+	}
+	t.timer = time.AfterFunc(d, func() {
+		cc.events <- func() {
+			if t.stopped {
+				return
+			}
+			if time.Now().Before(at) {
+				cc.wait(t, at, fire)
+				return
+			}
+			fire()
+		}
+	})
+}
+
+// Stop prevents the timer from firing. Like AfterFunc callbacks, it must only
+// be called from the conversation goroutine.
+func (t *Timer) Stop() {
+	t.stopped = true
+	t.timer.Stop()
+}
+
+// Send posts a message to the chat and returns its id.
+func (cc *Conversation) Send(reply *Reply) (int, error) {
+	msg := tgbotapi.NewMessage(cc.chatId, reply.Text)
+	if len(reply.Keyboard) > 0 {
+		msg.ReplyMarkup = keyboardMarkup(reply.Keyboard)
+	}
+	sent, err := cc.engine.tgbot.Send(msg)
+	if err != nil {
+		return 0, err
+	}
+	return sent.MessageID, nil
+}
+
+// SendDocument uploads a file to the chat.
+func (cc *Conversation) SendDocument(fileName string, content []byte) error {
+	doc := tgbotapi.NewDocumentUpload(cc.chatId, tgbotapi.FileBytes{Name: fileName, Bytes: content})
+	_, err := cc.engine.tgbot.Send(doc)
+	return err
+}
+
+func (cc *Conversation) send(reply string) {
+	if reply == "" {
+		return
+	}
+	if _, err := cc.Send(&Reply{Text: reply}); err != nil {
+		log.Printf("[ERROR Cannot send message] ChatId {%d} Error {%s}", cc.chatId, err)
+	}
+}
+
+// Edit replaces the text and the keyboard of a message sent to the chat.
+// Edits leaving the message unchanged are not errors.
+func (cc *Conversation) Edit(messageId int, reply *Reply) error {
+	msg := tgbotapi.NewEditMessageText(cc.chatId, messageId, reply.Text)
+	if len(reply.Keyboard) > 0 {
+		markup := keyboardMarkup(reply.Keyboard)
+		msg.ReplyMarkup = &markup
+	}
+	_, err := cc.engine.tgbot.Send(msg)
+	if err != nil && isNotModified(err) {
+		return nil
+	}
+	return err
+}
+
+func keyboardMarkup(keyboard [][]Button) tgbotapi.InlineKeyboardMarkup {
+	rows := [][]tgbotapi.InlineKeyboardButton{}
+	for _, buttons := range keyboard {
+		row := []tgbotapi.InlineKeyboardButton{}
+		for _, b := range buttons {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(b.Text, b.Data))
+		}
+		rows = append(rows, row)
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+func (cc *Conversation) dispatchMessage(msg *tgbotapi.Message) {
+	if msg.Document != nil {
+		cc.dispatchDocument(msg)
+		return
+	}
+	text, ok := cc.engine.stripBotName(msg.Text)
+	if !ok {
+		log.Printf("[Ignoring command for another bot] ChatId {%d} Text {%s}", cc.chatId, msg.Text)
+		return
+	}
+	if isLangCommand(text) {
+		cc.send(cc.setLanguage(newUser(msg.From), text))
+		return
+	}
+	for _, bot := range cc.bots {
+		reply := bot.OnUpdate(newUser(msg.From), text)
+		cc.send(reply)
+	}
+}
+
+// stripBotName removes the "@botname" suffix which Telegram adds to the
+// commands sent in groups, e.g. "/list@mybot all". It returns false for the
+// commands addressed to other bots.
+func (engine *Gotto) stripBotName(text string) (string, bool) {
+	if !strings.HasPrefix(text, "/") {
+		return text, true
+	}
+	end := strings.IndexAny(text, " \t\n")
+	if end < 0 {
+		end = len(text)
+	}
+	at := strings.Index(text[:end], "@")
+	if at < 0 {
+		return text, true
+	}
+	if !strings.EqualFold(text[at+1:end], engine.tgbot.Self.UserName) {
+		return "", false
+	}
+	return text[:at] + text[end:], true
+}
+
+func (cc *Conversation) dispatchDocument(msg *tgbotapi.Message) {
+	var content []byte
+	for _, bot := range cc.bots {
+		db, ok := bot.(DocumentBot)
+		if !ok {
+			continue
+		}
+		if content == nil {
+			var err error
+			content, err = cc.download(msg.Document)
+			if err != nil {
+				log.Printf("[ERROR Cannot download document] ChatId {%d} FileName {%s} Error {%s}", cc.chatId, msg.Document.FileName, err)
+				cc.send(cc.Locale(newUser(msg.From)).Sprintf("Cannot read the document '%s'", msg.Document.FileName))
+				return
+			}
+		}
+		reply := db.OnDocument(newUser(msg.From), msg.Document.FileName, content)
+		cc.send(reply)
+	}
+}
+
+func (cc *Conversation) download(doc *tgbotapi.Document) ([]byte, error) {
+	if doc.FileSize > maxDocumentSize {
+		return nil, fmt.Errorf("document too large (%d bytes)", doc.FileSize)
+	}
+	url, err := cc.engine.tgbot.GetFileDirectURL(doc.FileID)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	content, err := ioutil.ReadAll(io.LimitReader(resp.Body, int64(maxDocumentSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxDocumentSize {
+		return nil, fmt.Errorf("document too large")
+	}
+	return content, nil
+}
+
+func (cc *Conversation) dispatchCallback(query *tgbotapi.CallbackQuery) {
+	for _, bot := range cc.bots {
+		cb, ok := bot.(CallbackBot)
+		if !ok {
+			continue
+		}
+		reply := cb.OnCallback(newUser(query.From), query.Data)
+		if reply == nil {
+			continue
+		}
+		if err := cc.Edit(query.Message.MessageID, reply); err != nil {
+			log.Printf("[ERROR Cannot edit message] ChatId {%d} MessageId {%d} Error {%s}", cc.chatId, query.Message.MessageID, err)
+		}
+	}
+	// stop the progress indicator on the client
+	if _, err := cc.engine.tgbot.AnswerCallbackQuery(tgbotapi.NewCallback(query.ID, "")); err != nil {
+		log.Printf("[ERROR Cannot answer callback] ChatId {%d} Error {%s}", cc.chatId, err)
+	}
 }
 
 func (engine *Gotto) newConversation(chatId int64) (*Conversation, error) {
 	cc := &Conversation{}
 	cc.channel = make(chan *tgbotapi.Update)
+	cc.events = make(chan func())
+	cc.engine = engine
 	cc.chatId = chatId
 	cc.config = engine.config
 	cc.bots = []GottoBot{}
 	// create the workspace
-	workspace := "./workspace/" + fmt.Sprint(chatId)
+	workspace := workspaceRoot + "/" + fmt.Sprint(chatId)
 	err := os.MkdirAll(workspace, os.ModePerm)
 	if err != nil {
 		log.Printf("[ERROR Cannot create workspace] ChatId {%d} Workspace {%s}", chatId, workspace)
 		return nil, fmt.Errorf("cannot create workspace dir - %s", err)
 	}
 	cc.workspace = workspace
+	cc.loadPins()
+	cc.loadLocales()
 	// initialize individual bots
 	for _, f := range engine.factories {
-		bot, err := f.CreateBot(cc.workspace)
+		bot, err := f.CreateBot(cc)
 		if err != nil {
 			log.Printf("[ERROR Cannot initialize bot] BotFactory {%+v} ChatId {%d} Workspace {%s}", f, cc.chatId, cc.workspace)
 			continue
@@ -67,13 +427,15 @@ func (engine *Gotto) newConversation(chatId int64) (*Conversation, error) {
 	// start message dispatching
 	go func(conversation *Conversation) {
 		for {
-			upd := <-conversation.channel
-			for _, bot := range conversation.bots {
-				reply := bot.OnUpdate(fmt.Sprint(upd.Message.From.ID), fmt.Sprint(upd.Message.From), upd.Message.Text)
-				if reply != "" {
-					msg := tgbotapi.NewMessage(conversation.chatId, reply)
-					engine.tgbot.Send(msg)
+			select {
+			case upd := <-conversation.channel:
+				if upd.CallbackQuery != nil {
+					conversation.dispatchCallback(upd.CallbackQuery)
+				} else {
+					conversation.dispatchMessage(upd.Message)
 				}
+			case event := <-conversation.events:
+				event()
 			}
 		}
 	}(cc)
@@ -102,11 +464,34 @@ func initConfig(path *string) (*Config, error) {
 	}
 	defer file.Close()
 
-	config := &Config{}
-	dec := toml.NewDecoder(file)
-	if err := dec.Decode(config); err != nil {
+	tree, err := toml.LoadReader(file)
+	if err != nil {
+		return nil, err
+	}
+	config := &Config{tree: tree}
+	if err := tree.Unmarshal(config); err != nil {
 		return nil, err
 	}
+	if config.Backup.Dir == "" {
+		config.Backup.Dir = "./backups"
+	}
+	if config.Backup.KeepDaily < 0 || config.Backup.KeepWeekly < 0 {
+		return nil, fmt.Errorf("invalid backup retention, keepDaily and keepWeekly cannot be negative")
+	}
+	if config.Backup.KeepDaily == 0 {
+		config.Backup.KeepDaily = 7
+	}
+	if config.Backup.KeepWeekly == 0 {
+		config.Backup.KeepWeekly = 4
+	}
+	if lang, ok := supportedLanguage(config.Bot.Language); ok {
+		config.Bot.Language = lang
+	} else {
+		if config.Bot.Language != "" {
+			log.Printf("Unsupported language %s, using %s", config.Bot.Language, defaultLanguage)
+		}
+		config.Bot.Language = defaultLanguage
+	}
 
 	return config, nil
 }
@@ -136,6 +521,15 @@ func NewGotto(configPath *string) (*Gotto, error) {
 		return nil, err
 	}
 
+	var backups *Schedule
+	if config.Backup.Schedule != "" {
+		backups, err = ParseSchedule(config.Backup.Schedule)
+		if err != nil {
+			log.Printf("Cannot read the backup schedule - %s", err)
+			return nil, err
+		}
+	}
+
 	bot, err := initBot(config)
 	if err != nil {
 		log.Printf("Cannot initialize the bot - %s", err)
@@ -151,14 +545,71 @@ func NewGotto(configPath *string) (*Gotto, error) {
 		config:        config,
 		conversations: make(map[int64]*Conversation),
 		factories:     []GottoBotFactory{},
+		backups:       backups,
 	}, nil
 }
 
+// BotConfig decodes the configuration section with the given name into v.
+// Values missing from the file leave the corresponding fields of v untouched,
+// including the keys of the map fields.
+func (engine *Gotto) BotConfig(name string, v interface{}) error {
+	section, ok := engine.config.tree.Get(name).(*toml.Tree)
+	if !ok {
+		return nil
+	}
+	defaults := mapFields(v)
+	if err := section.Unmarshal(v); err != nil {
+		return fmt.Errorf("invalid configuration for '%s' - %s", name, err)
+	}
+	// the decoder replaces the maps: merge them over the previous values
+	for i, previous := range defaults {
+		field := reflect.ValueOf(v).Elem().Field(i)
+		if field.IsNil() {
+			field.Set(reflect.MakeMap(field.Type()))
+		}
+		iter := previous.MapRange()
+		for iter.Next() {
+			if !field.MapIndex(iter.Key()).IsValid() {
+				field.SetMapIndex(iter.Key(), iter.Value())
+			}
+		}
+	}
+	return nil
+}
+
+// mapFields copies the map fields of the struct v points to, by index.
+func mapFields(v interface{}) map[int]reflect.Value {
+	result := make(map[int]reflect.Value)
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return result
+	}
+	value = value.Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		if field.Kind() != reflect.Map || field.IsNil() || !field.CanSet() {
+			continue
+		}
+		copied := reflect.MakeMap(field.Type())
+		iter := field.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(iter.Key(), iter.Value())
+		}
+		result[i] = copied
+	}
+	return result
+}
+
 func (engine *Gotto) RegisterBot(factory GottoBotFactory) {
 	engine.factories = append(engine.factories, factory)
 }
 
 func (engine *Gotto) Start() {
+	if engine.backups != nil {
+		log.Printf("[Scheduling backups] Schedule {%s} Dir {%s}", engine.backups, engine.config.Backup.Dir)
+		go engine.scheduleBackups(engine.backups)
+	}
+
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
 
@@ -169,7 +620,20 @@ func (engine *Gotto) Start() {
 	}
 
 	for update := range updates {
-		if update.Message == nil { // ignore any non-Message Updates
+		if query := update.CallbackQuery; query != nil && query.Message != nil {
+			if !engine.config.isAllowed(query.From.ID) {
+				log.Printf("[Ignoring callback] User {%s} UserId {%d} Data {%s}", query.From, query.From.ID, query.Data)
+				continue
+			}
+			log.Printf("[Processing callback] User {%s} Data {%s} Chat {%d}", query.From, query.Data, query.Message.Chat.ID)
+			conversation, err := engine.getConversation(query.Message.Chat.ID)
+			if err != nil {
+				log.Printf("[ERROR Cannot get Conversation] Chat {%d}", query.Message.Chat.ID)
+				continue
+			}
+			upd := update
+			conversation.channel <- &upd
+		} else if update.Message == nil { // ignore any other non-Message Updates
 			continue
 		} else if engine.config.isAllowed(update.Message.From.ID) {
 			msg := update.Message
@@ -180,7 +644,8 @@ func (engine *Gotto) Start() {
 				continue
 			}
 			// dispatch the update to the right conversation
-			conversation.channel <- &update
+			upd := update
+			conversation.channel <- &upd
 		} else {
 			log.Printf("[Ignoring] User {%s} UserId {%d} Text {%s} Chat {%d}", update.Message.From,
 				update.Message.From.ID, update.Message.Text, update.Message.Chat.ID)
@@ -0,0 +1,193 @@
+package gotto
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The bots write their replies in English and translate them with catalogs
+// mapping the English messages, format strings included, to their
+// translations. Each user gets the language of their Telegram client, if it
+// is supported, unless they choose another one with /lang.
+
+// defaultLanguage is the language the messages are written in
+const defaultLanguage string = "en"
+
+// localesFileName stores, in the workspace of the chat, the languages chosen
+// by the users with /lang.
+const localesFileName string = "locales.json"
+
+// catalogs maps the languages to their translations.
+var catalogs map[string]map[string]string = make(map[string]map[string]string)
+
+// AddMessages adds translations, by English message, to the catalog of a
+// language. It is not safe for concurrent use: call it before starting the
+// engine, e.g. from an init function.
+func AddMessages(lang string, messages map[string]string) {
+	lang = strings.ToLower(lang)
+	if catalogs[lang] == nil {
+		catalogs[lang] = make(map[string]string)
+	}
+	for message, translation := range messages {
+		catalogs[lang][message] = translation
+	}
+}
+
+// Languages returns the supported languages, sorted.
+func Languages() []string {
+	result := []string{defaultLanguage}
+	for lang := range catalogs {
+		if lang != defaultLanguage {
+			result = append(result, lang)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// supportedLanguage returns the supported language of a language code like
+// "it" or "en-US".
+func supportedLanguage(code string) (string, bool) {
+	lang := strings.ToLower(strings.TrimSpace(code))
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	if _, ok := catalogs[lang]; ok || lang == defaultLanguage {
+		return lang, true
+	}
+	return "", false
+}
+
+// Locale is the language of a user, e.g. "it".
+type Locale string
+
+// Sprintf translates a message, falling back to English if the catalog has
+// no translation for it, and formats it like fmt.Sprintf. Messages without
+// arguments are not formatted.
+func (l Locale) Sprintf(format string, args ...interface{}) string {
+	if translation, ok := catalogs[string(l)][format]; ok {
+		format = translation
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// IsYes tells whether an answer is "yes", in English or in the language of
+// the locale, ignoring case and accents.
+func (l Locale) IsYes(answer string) bool {
+	return l.answers(answer, "yes")
+}
+
+// IsNo tells whether an answer is "no", in English or in the language of the
+// locale, ignoring case and accents.
+func (l Locale) IsNo(answer string) bool {
+	return l.answers(answer, "no")
+}
+
+func (l Locale) answers(answer string, keyword string) bool {
+	answer = foldAccents(strings.ToLower(strings.TrimSpace(answer)))
+	return answer == keyword || answer == foldAccents(strings.ToLower(l.Sprintf(keyword)))
+}
+
+var accents *strings.Replacer = strings.NewReplacer(
+	"à", "a", "á", "a", "è", "e", "é", "e", "ì", "i", "í", "i",
+	"ò", "o", "ó", "o", "ù", "u", "ú", "u")
+
+func foldAccents(s string) string {
+	return accents.Replace(s)
+}
+
+// Locale returns the language chosen by a user with /lang, else the one of
+// their Telegram client if supported, else the default one of the
+// configuration. It can be called from any goroutine.
+func (cc *Conversation) Locale(user User) Locale {
+	cc.localesMu.Lock()
+	lang, ok := cc.locales[user.Id]
+	cc.localesMu.Unlock()
+	if ok {
+		return Locale(lang)
+	}
+	if lang, ok := supportedLanguage(user.LanguageCode); ok {
+		return Locale(lang)
+	}
+	return Locale(cc.config.Bot.Language)
+}
+
+func (cc *Conversation) loadLocales() {
+	cc.locales = make(map[int]string)
+	data, err := ioutil.ReadFile(filepath.Join(cc.workspace, localesFileName))
+	if os.IsNotExist(err) {
+		return
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &cc.locales)
+	}
+	if err != nil {
+		log.Printf("[ERROR Cannot read languages] ChatId {%d} Error {%s}", cc.chatId, err)
+	}
+}
+
+func (cc *Conversation) saveLocales() error {
+	data, err := json.Marshal(cc.locales)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(cc.workspace, localesFileName), data, 0644)
+}
+
+// langCommand is handled by the engine for all the bots
+const langCommand string = "/lang"
+
+// autoLanguage goes back to the language of the Telegram client
+const autoLanguage string = "auto"
+
+// setLanguage handles /lang, which prints the language of the user, and
+// /lang <language>, which changes it. The reply is in the new language.
+func (cc *Conversation) setLanguage(user User, text string) string {
+	args := strings.Fields(text)
+	languages := strings.Join(Languages(), "|")
+	if len(args) == 1 {
+		return cc.Locale(user).Sprintf("Your language is %s. Change it with /lang <%s>, or use the one of Telegram with /lang %s", cc.Locale(user), languages, autoLanguage)
+	}
+	if len(args) > 2 {
+		return cc.Locale(user).Sprintf("Usage: /lang <%s>", languages)
+	}
+	cc.localesMu.Lock()
+	previous, had := cc.locales[user.Id]
+	if strings.EqualFold(args[1], autoLanguage) {
+		delete(cc.locales, user.Id)
+	} else if lang, ok := supportedLanguage(args[1]); ok {
+		cc.locales[user.Id] = lang
+	} else {
+		cc.localesMu.Unlock()
+		return cc.Locale(user).Sprintf("Unsupported language %s. Choose one of: %s", args[1], strings.Join(Languages(), ", "))
+	}
+	err := cc.saveLocales()
+	if err != nil {
+		if had {
+			cc.locales[user.Id] = previous
+		} else {
+			delete(cc.locales, user.Id)
+		}
+	}
+	cc.localesMu.Unlock()
+	if err != nil {
+		log.Printf("[ERROR Cannot save languages] ChatId {%d} Error {%s}", cc.chatId, err)
+		return cc.Locale(user).Sprintf("Cannot change your language. An error occurred")
+	}
+	return cc.Locale(user).Sprintf("Your language is now %s", cc.Locale(user))
+}
+
+// isLangCommand tells whether a message is /lang, with or without arguments.
+func isLangCommand(text string) bool {
+	args := strings.Fields(text)
+	return len(args) > 0 && strings.EqualFold(args[0], langCommand)
+}
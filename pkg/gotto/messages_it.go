@@ -0,0 +1,15 @@
+package gotto
+
+func init() {
+	AddMessages("it", map[string]string{
+		"yes": "sì",
+		"no":  "no",
+
+		"Your language is %s. Change it with /lang <%s>, or use the one of Telegram with /lang %s": "La tua lingua è %s. Cambiala con /lang <%s>, o usa quella di Telegram con /lang %s",
+		"Usage: /lang <%s>":                              "Uso: /lang <%s>",
+		"Unsupported language %s. Choose one of: %s":     "Lingua %s non supportata. Scegli tra: %s",
+		"Cannot change your language. An error occurred": "Impossibile cambiare la tua lingua. Si è verificato un errore",
+		"Your language is now %s":                        "Ora la tua lingua è %s",
+		"Cannot read the document '%s'":                  "Impossibile leggere il documento '%s'",
+	})
+}
@@ -0,0 +1,128 @@
+package gotto
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// pinsFileName stores, in the workspace of the chat, the ids of the messages
+// pinned by the bots, so that they keep being updated after a restart.
+const pinsFileName string = "pinned.json"
+
+func (cc *Conversation) loadPins() {
+	cc.pins = make(map[string]int)
+	data, err := ioutil.ReadFile(filepath.Join(cc.workspace, pinsFileName))
+	if os.IsNotExist(err) {
+		return
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &cc.pins)
+	}
+	if err != nil {
+		log.Printf("[ERROR Cannot read pinned messages] ChatId {%d} Error {%s}", cc.chatId, err)
+	}
+}
+
+func (cc *Conversation) savePins() error {
+	data, err := json.Marshal(cc.pins)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(cc.workspace, pinsFileName), data, 0644)
+}
+
+// Pinned tells whether a message was pinned with the given key.
+func (cc *Conversation) Pinned(key string) bool {
+	cc.pinsMu.Lock()
+	defer cc.pinsMu.Unlock()
+	_, ok := cc.pins[key]
+	return ok
+}
+
+// Pin posts and pins a message identified by a key chosen by the bot. If a
+// message was already pinned with the same key it is edited in place instead.
+func (cc *Conversation) Pin(key string, reply *Reply) error {
+	cc.pinsMu.Lock()
+	defer cc.pinsMu.Unlock()
+	if id, ok := cc.pins[key]; ok {
+		if err := cc.Edit(id, reply); err == nil {
+			return nil
+		}
+		// the message may have been deleted, post a new one
+	}
+	id, err := cc.Send(reply)
+	if err != nil {
+		return err
+	}
+	pin := tgbotapi.PinChatMessageConfig{ChatID: cc.chatId, MessageID: id, DisableNotification: true}
+	if _, err := cc.engine.tgbot.PinChatMessage(pin); err != nil {
+		return fmt.Errorf("cannot pin message - %s", err)
+	}
+	cc.pins[key] = id
+	return cc.savePins()
+}
+
+// UpdatePinned edits the message pinned with the given key, if any. It can be
+// called from any goroutine.
+func (cc *Conversation) UpdatePinned(key string, reply *Reply) error {
+	cc.pinsMu.Lock()
+	defer cc.pinsMu.Unlock()
+	id, ok := cc.pins[key]
+	if !ok {
+		return nil
+	}
+	return cc.Edit(id, reply)
+}
+
+// MovePinned changes the key of a pinned message.
+func (cc *Conversation) MovePinned(from string, to string) error {
+	cc.pinsMu.Lock()
+	defer cc.pinsMu.Unlock()
+	id, ok := cc.pins[from]
+	if !ok {
+		return nil
+	}
+	delete(cc.pins, from)
+	cc.pins[to] = id
+	return cc.savePins()
+}
+
+// Unpin unpins the message pinned with the given key, replacing its content
+// with the given reply if not nil. The message itself is left in the chat.
+func (cc *Conversation) Unpin(key string, reply *Reply) error {
+	cc.pinsMu.Lock()
+	defer cc.pinsMu.Unlock()
+	id, ok := cc.pins[key]
+	if !ok {
+		return nil
+	}
+	delete(cc.pins, key)
+	// the client library cannot unpin a given message, call the API directly
+	params := url.Values{}
+	params.Add("chat_id", strconv.FormatInt(cc.chatId, 10))
+	params.Add("message_id", strconv.Itoa(id))
+	if _, err := cc.engine.tgbot.MakeRequest("unpinChatMessage", params); err != nil {
+		log.Printf("[ERROR Cannot unpin message] ChatId {%d} MessageId {%d} Error {%s}", cc.chatId, id, err)
+	}
+	if reply != nil {
+		if err := cc.Edit(id, reply); err != nil {
+			log.Printf("[ERROR Cannot edit unpinned message] ChatId {%d} MessageId {%d} Error {%s}", cc.chatId, id, err)
+		}
+	}
+	return cc.savePins()
+}
+
+// isNotModified tells whether Telegram refused an edit because the message
+// already had the same content.
+func isNotModified(err error) bool {
+	return strings.Contains(err.Error(), "message is not modified")
+}
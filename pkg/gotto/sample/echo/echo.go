@@ -17,11 +17,11 @@ func NewFactory() *EchoBotFactory {
 	return &EchoBotFactory{}
 }
 
-func (*EchoBotFactory) CreateBot(workspace string) (gotto.GottoBot, error) {
-	log.Printf("[New EchoBot created] Workspace {%s}", workspace)
-	return &EchoBot{workspace: workspace}, nil
+func (*EchoBotFactory) CreateBot(conversation *gotto.Conversation) (gotto.GottoBot, error) {
+	log.Printf("[New EchoBot created] Workspace {%s}", conversation.Workspace())
+	return &EchoBot{workspace: conversation.Workspace()}, nil
 }
 
-func (bot *EchoBot) OnUpdate(userId string, userName string, message string) string {
+func (bot *EchoBot) OnUpdate(user gotto.User, message string) string {
 	return message
 }
@@ -0,0 +1,87 @@
+package gotto
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Schedule describes when a recurring job must run. Supported forms are
+// "every <duration>" (e.g. "every 6h"), "daily <hh:mm>" and
+// "<weekday> <hh:mm>" (e.g. "mon 08:00").
+type Schedule struct {
+	every   time.Duration
+	weekday time.Weekday
+	daily   bool
+	hour    int
+	minute  int
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+func ParseSchedule(spec string) (*Schedule, error) {
+	fields := strings.Fields(strings.ToLower(spec))
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("invalid schedule '%s'", spec)
+	}
+	if fields[0] == "every" {
+		d, err := time.ParseDuration(fields[1])
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid schedule interval '%s'", fields[1])
+		}
+		return &Schedule{every: d}, nil
+	}
+	at, err := time.Parse("15:04", fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule time '%s'", fields[1])
+	}
+	sched := &Schedule{hour: at.Hour(), minute: at.Minute()}
+	if fields[0] == "daily" {
+		sched.daily = true
+		return sched, nil
+	}
+	wd, ok := weekdays[fields[0]]
+	if !ok {
+		return nil, fmt.Errorf("invalid schedule day '%s'", fields[0])
+	}
+	sched.weekday = wd
+	return sched, nil
+}
+
+// Next returns the first activation strictly after t.
+func (s *Schedule) Next(t time.Time) time.Time {
+	if s.every > 0 {
+		return t.Add(s.every)
+	}
+	next := time.Date(t.Year(), t.Month(), t.Day(), s.hour, s.minute, 0, 0, t.Location())
+	if s.daily {
+		if !next.After(t) {
+			next = next.AddDate(0, 0, 1)
+		}
+		return next
+	}
+	next = next.AddDate(0, 0, (int(s.weekday)-int(next.Weekday())+7)%7)
+	if !next.After(t) {
+		next = next.AddDate(0, 0, 7)
+	}
+	return next
+}
+
+func (s *Schedule) String() string {
+	switch {
+	case s.every > 0:
+		return fmt.Sprintf("every %s", s.every)
+	case s.daily:
+		return fmt.Sprintf("daily %02d:%02d", s.hour, s.minute)
+	default:
+		return fmt.Sprintf("%s %02d:%02d", strings.ToLower(s.weekday.String()[:3]), s.hour, s.minute)
+	}
+}
@@ -0,0 +1,225 @@
+// Package quantity reads the quantity and the price of shopping list items,
+// e.g. "2x milk €1.20", "500g flour" or "eggs 3.50$".
+package quantity
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// reAmount matches a leading amount with its unit: "2x", "2 x", "500g",
+// "1.5 kg". A number alone is part of the name, e.g. "10 Downing Street".
+var reAmount *regexp.Regexp = regexp.MustCompile(`(?i)^(\d+(?:[.,]\d+)?)\s*(x|pcs|pz|mg|g|kg|ml|cl|dl|l|oz|lb)\s+(\S.*)$`)
+
+// reTrailingCount matches a trailing count: "milk x2"
+var reTrailingCount *regexp.Regexp = regexp.MustCompile(`(?i)^(\S.*?)\s+x\s*(\d+)$`)
+
+// rePrice matches a trailing price with the currency before or after it:
+// "€1.20", "1,20 €", "$3", "3 eur"
+var rePrice *regexp.Regexp = regexp.MustCompile(`(?i)^(\S.*?)\s+(?:(€|\$|£|eur|usd|gbp)\s*(\d+(?:[.,]\d{1,2})?)|(\d+(?:[.,]\d{1,2})?)\s*(€|\$|£|eur|usd|gbp))$`)
+
+var currencies = map[string]string{
+	"€": "€", "eur": "€",
+	"$": "$", "usd": "$",
+	"£": "£", "gbp": "£",
+}
+
+// countUnits are the units of items counted in pieces
+var countUnits = map[string]bool{"": true, "x": true, "pcs": true, "pz": true}
+
+// unitScales converts the units of the same measure to a common one, so that
+// e.g. "500g flour" and "1kg flour" can be merged.
+var unitScales = map[string]struct {
+	base  string
+	scale float64
+}{
+	"mg": {"g", 0.001}, "g": {"g", 1}, "kg": {"g", 1000},
+	"ml": {"l", 0.001}, "cl": {"l", 0.01}, "dl": {"l", 0.1}, "l": {"l", 1},
+}
+
+// convert returns the amount in the given unit, if the units measure the
+// same thing.
+func convert(amount float64, from string, to string) (float64, bool) {
+	if from == to {
+		return amount, true
+	}
+	f, ok1 := unitScales[from]
+	t, ok2 := unitScales[to]
+	if !ok1 || !ok2 || f.base != t.base {
+		return 0, false
+	}
+	return amount * f.scale / t.scale, true
+}
+
+// Quantity is an item split into its parts. Amount is zero and Currency is
+// empty when they are not given.
+type Quantity struct {
+	Amount float64
+	// Unit is lowercase and empty for items counted in pieces
+	Unit string
+	Name string
+	// Price is the price of the whole amount, in cents
+	Price    int64
+	Currency string
+}
+
+// Parse splits an item into its quantity, name and price. Items without any
+// of them are returned as a name only.
+func Parse(text string) Quantity {
+	q := Quantity{Name: strings.TrimSpace(text)}
+	if args := rePrice.FindStringSubmatch(q.Name); args != nil {
+		amount, currency := args[3], args[2]
+		if amount == "" {
+			amount, currency = args[4], args[5]
+		}
+		if cents, ok := parseCents(amount); ok {
+			q.Name, q.Price, q.Currency = args[1], cents, currencies[strings.ToLower(currency)]
+		}
+	}
+	if args := reAmount.FindStringSubmatch(q.Name); args != nil {
+		if amount, err := strconv.ParseFloat(strings.Replace(args[1], ",", ".", 1), 64); err == nil && amount > 0 {
+			q.Amount, q.Unit, q.Name = amount, strings.ToLower(args[2]), args[3]
+		}
+	} else if args := reTrailingCount.FindStringSubmatch(q.Name); args != nil {
+		if amount, err := strconv.ParseFloat(args[2], 64); err == nil && amount > 0 {
+			q.Amount, q.Name = amount, args[1]
+		}
+	}
+	if countUnits[q.Unit] {
+		q.Unit = ""
+	}
+	return q
+}
+
+func parseCents(s string) (int64, bool) {
+	parts := strings.SplitN(strings.Replace(s, ",", ".", 1), ".", 2)
+	units, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	cents := int64(0)
+	if len(parts) == 2 {
+		decimals := parts[1]
+		if len(decimals) == 1 {
+			decimals += "0"
+		}
+		if cents, err = strconv.ParseInt(decimals, 10, 64); err != nil {
+			return 0, false
+		}
+	}
+	return units*100 + cents, true
+}
+
+// Explicit tells whether a quantity or a price was given.
+func (q Quantity) Explicit() bool {
+	return q.Amount > 0 || q.Currency != ""
+}
+
+// Count is the number of pieces of an item: its amount if counted in
+// pieces, one otherwise.
+func (q Quantity) Count() float64 {
+	if q.Unit == "" && q.Amount > 0 {
+		return q.Amount
+	}
+	return 1
+}
+
+// Merge sums two quantities of the same product, i.e. with the same name,
+// ignoring case, and units of the same measure. The result keeps the unit of
+// q. Prices are summed, so either both or none of them must be given, in the
+// same currency.
+func (q Quantity) Merge(other Quantity) (Quantity, bool) {
+	if !strings.EqualFold(q.Name, other.Name) || q.Currency != other.Currency {
+		return q, false
+	}
+	merged := q
+	if q.Unit == "" && other.Unit == "" {
+		merged.Amount = q.Count() + other.Count()
+	} else {
+		amount, ok := convert(other.Amount, other.Unit, q.Unit)
+		if !ok {
+			return q, false
+		}
+		merged.Amount = q.Amount + amount
+	}
+	merged.Price += other.Price
+	return merged, true
+}
+
+func (q Quantity) String() string {
+	s := q.Name
+	switch {
+	case q.Amount > 0 && q.Unit == "":
+		s = FormatAmount(q.Amount) + "x " + s
+	case q.Amount > 0:
+		s = FormatAmount(q.Amount) + q.Unit + " " + s
+	}
+	if q.Currency != "" {
+		s += " " + FormatPrice(q.Price, q.Currency)
+	}
+	return s
+}
+
+// FormatAmount prints an amount with up to three decimals, hiding the
+// rounding errors of the conversions.
+func FormatAmount(amount float64) string {
+	return strconv.FormatFloat(math.Round(amount*1000)/1000, 'f', -1, 64)
+}
+
+// FormatPrice prints an amount of cents, e.g. "€1.20".
+func FormatPrice(cents int64, currency string) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%s%d.%02d", sign, currency, cents/100, cents%100)
+}
+
+// Total sums the pieces and the prices of several items.
+type Total struct {
+	Count float64
+	// Prices maps each currency to the sum of the prices in it
+	Prices map[string]int64
+}
+
+func (t *Total) Add(q Quantity) {
+	t.Count += q.Count()
+	if q.Currency == "" {
+		return
+	}
+	if t.Prices == nil {
+		t.Prices = make(map[string]int64)
+	}
+	t.Prices[q.Currency] += q.Price
+}
+
+// String prints the total, e.g. "5 items, €12.40".
+func (t Total) String() string {
+	s := FormatAmount(t.Count) + " items"
+	if t.Count == 1 {
+		s = "1 item"
+	}
+	for _, price := range t.PriceList() {
+		s += ", " + price
+	}
+	return s
+}
+
+// PriceList prints the sum of the prices in each currency, sorted by
+// currency.
+func (t Total) PriceList() []string {
+	keys := make([]string, 0, len(t.Prices))
+	for currency := range t.Prices {
+		keys = append(keys, currency)
+	}
+	sort.Strings(keys)
+	result := make([]string, 0, len(keys))
+	for _, currency := range keys {
+		result = append(result, FormatPrice(t.Prices[currency], currency))
+	}
+	return result
+}
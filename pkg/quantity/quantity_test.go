@@ -0,0 +1,93 @@
+package quantity
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text string
+		want Quantity
+	}{
+		{"milk", Quantity{Name: "milk"}},
+		{"2x milk", Quantity{Amount: 2, Name: "milk"}},
+		{"2 x milk", Quantity{Amount: 2, Name: "milk"}},
+		{"3 pcs eggs", Quantity{Amount: 3, Name: "eggs"}},
+		{"milk x2", Quantity{Amount: 2, Name: "milk"}},
+		{"500g flour", Quantity{Amount: 500, Unit: "g", Name: "flour"}},
+		{"1,5 KG flour", Quantity{Amount: 1.5, Unit: "kg", Name: "flour"}},
+		{"2 lemons", Quantity{Name: "2 lemons"}},
+		{"10 Downing Street", Quantity{Name: "10 Downing Street"}},
+		{"0x milk", Quantity{Name: "0x milk"}},
+		{"milk €1.20", Quantity{Name: "milk", Price: 120, Currency: "€"}},
+		{"eggs 3.5$", Quantity{Name: "eggs", Price: 350, Currency: "$"}},
+		{"bread 2 eur", Quantity{Name: "bread", Price: 200, Currency: "€"}},
+		{"2x milk £1", Quantity{Amount: 2, Name: "milk", Price: 100, Currency: "£"}},
+	}
+	for _, tt := range tests {
+		if got := Parse(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string
+		ok   bool
+	}{
+		{"milk €0.60", "2x milk €1.20", "3x milk €1.80", true},
+		{"2x Milk", "milk x3", "5x Milk", true},
+		{"500g flour", "1kg flour", "1500g flour", true},
+		{"1l water", "250ml water", "1.25l water", true},
+		{"1l water", "1kg water", "", false},
+		{"2x milk", "2x bread", "", false},
+		{"milk €1", "milk $1", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := Parse(tt.a).Merge(Parse(tt.b))
+		if ok != tt.ok || (ok && got.String() != tt.want) {
+			t.Errorf("Merge(%q, %q) = %q, %v, want %q, %v", tt.a, tt.b, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestTotal(t *testing.T) {
+	tests := []struct {
+		items []string
+		want  string
+	}{
+		{nil, "0 items"},
+		{[]string{"milk"}, "1 item"},
+		{[]string{"2x milk €1.20", "500g flour", "10 Downing Street"}, "4 items, €1.20"},
+		{[]string{"milk €1", "eggs $2.50", "bread €0.5"}, "3 items, $2.50, €1.50"},
+	}
+	for _, tt := range tests {
+		total := Total{}
+		for _, item := range tt.items {
+			total.Add(Parse(item))
+		}
+		if got := total.String(); got != tt.want {
+			t.Errorf("total of %q = %q, want %q", tt.items, got, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		got, want string
+	}{
+		{FormatAmount(1.25), "1.25"},
+		{FormatAmount(0.1 + 0.2), "0.3"},
+		{FormatAmount(3), "3"},
+		{FormatPrice(120, "€"), "€1.20"},
+		{FormatPrice(-5, "$"), "-$0.05"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("got %q, want %q", tt.got, tt.want)
+		}
+	}
+}
@@ -1,16 +1,31 @@
 package main
 
 import (
+	"bytes"
 	"flag"
+	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/gvisco/vi.sco/pkg/bots/gottolists"
 	"github.com/gvisco/vi.sco/pkg/gotto"
+	"github.com/gvisco/vi.sco/pkg/gotto/fsm"
 	// "github.com/gvisco/vi.sco/pkg/gotto/sample/echo"
 )
 
+// machines are the state machines of the bots, by bot name
+var machines = map[string]*fsm.Machine{
+	"gottolists": gottolists.Machine(),
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "fsm-graph" {
+		fsmGraph(os.Args[2:])
+		return
+	}
+
 	config := flag.String("config", "./config.toml", "the .toml configuration file path")
 	flag.Parse()
 
@@ -20,6 +35,64 @@ func main() {
 		os.Exit(1)
 	}
 	// bot.RegisterBot(echo.NewFactory())
-	bot.RegisterBot(gottolists.NewFactory())
+	listsConfig := gottolists.DefaultConfig()
+	if err := bot.BotConfig("gottolists", &listsConfig); err != nil {
+		log.Panicf("Cannot initialize the bot - %s", err)
+	}
+	bot.RegisterBot(gottolists.NewFactory(listsConfig))
 	bot.Start()
 }
+
+// fsmGraph prints the state diagram of a bot. With -check it compares the
+// Mermaid diagram with the ```mermaid block of a markdown file instead, and
+// exits with an error if they differ.
+func fsmGraph(args []string) {
+	cmd := flag.NewFlagSet("fsm-graph", flag.ExitOnError)
+	name := cmd.String("bot", "gottolists", "the bot whose state machine is printed")
+	format := cmd.String("format", "mermaid", "the output format: mermaid or dot")
+	check := cmd.String("check", "", "a markdown file whose mermaid diagram must match the bot")
+	cmd.Parse(args)
+
+	machine, ok := machines[*name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown bot '%s'\n", *name)
+		os.Exit(2)
+	}
+
+	if *check != "" {
+		doc, err := ioutil.ReadFile(*check)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot read %s - %s\n", *check, err)
+			os.Exit(2)
+		}
+		if mermaidBlock(doc) != machine.Mermaid() {
+			fmt.Fprintf(os.Stderr, "The diagram in %s is out of date, regenerate it with:\n  vito fsm-graph -bot %s\n", *check, *name)
+			os.Exit(1)
+		}
+		return
+	}
+
+	switch *format {
+	case "mermaid":
+		fmt.Print(machine.Mermaid())
+	case "dot":
+		fmt.Print(machine.Dot())
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown format '%s'\n", *format)
+		os.Exit(2)
+	}
+}
+
+func mermaidBlock(doc []byte) string {
+	const fence = "```mermaid\n"
+	start := bytes.Index(doc, []byte(fence))
+	if start < 0 {
+		return ""
+	}
+	block := string(doc[start+len(fence):])
+	end := strings.Index(block, "```")
+	if end < 0 {
+		return ""
+	}
+	return block[:end]
+}